@@ -0,0 +1,82 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/websocket"
+
+	"whatsappBotGo/src/apikeys"
+)
+
+type keyContext struct{}
+
+// SetAPIKeys requires a bearer API key with the right scope on every
+// endpoint. Without a store the API is open.
+func (s *Server) SetAPIKeys(store *apikeys.Store) {
+	s.keys = store
+	if store == nil {
+		log.Printf("API authentication is disabled; anyone who can reach %s can use the API", s.httpServer.Addr)
+	}
+}
+
+// require wraps a handler so it only runs for a valid key granting scope.
+// The key is available to the handler through keyFromContext.
+func (s *Server) require(scope apikeys.Scope, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.keys == nil {
+			next(w, r)
+			return
+		}
+
+		token := bearerToken(r)
+		if token == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "api key required"})
+			return
+		}
+		key, err := s.keys.Authenticate(token)
+		if errors.Is(err, apikeys.ErrInvalidKey) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="api", error="invalid_token"`)
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid api key"})
+			return
+		}
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("authentication failed: %v", err)})
+			return
+		}
+		if !key.Allows(scope) {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": fmt.Sprintf("api key lacks the %s scope", scope)})
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), keyContext{}, key)))
+	}
+}
+
+// bearerToken reads the token from the Authorization header. Browsers can't
+// set headers on WebSocket connections, so upgrades may pass it as
+// ?access_token= instead.
+func bearerToken(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		scheme, token, ok := strings.Cut(auth, " ")
+		if ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+		return ""
+	}
+	if websocket.IsWebSocketUpgrade(r) {
+		return r.URL.Query().Get("access_token")
+	}
+	return ""
+}
+
+// keyFromContext returns the key a request was authenticated with, nil if
+// authentication is disabled
+func keyFromContext(ctx context.Context) *apikeys.Key {
+	key, _ := ctx.Value(keyContext{}).(*apikeys.Key)
+	return key
+}
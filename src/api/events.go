@@ -0,0 +1,186 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"whatsappBotGo/src/senders"
+	"whatsappBotGo/src/whats"
+)
+
+// EventVersion is the version of the WebSocket event schema. It changes when
+// a field is removed or changes meaning; new event types and fields are
+// added without a version change.
+const EventVersion = 1
+
+// EventType names the kind of payload an Event carries
+type EventType string
+
+const (
+	EventBot           EventType = "bot"                   // BotPayload
+	EventConnection    EventType = "connection"            // ConnectionPayload
+	EventQR            EventType = "qr"                    // QRPayload
+	EventPairing       EventType = "pairing"               // PairingPayload
+	EventMessage       EventType = "message"               // *whats.IncomingMessage
+	EventMessageSent   EventType = "message.sent"          // SentPayload
+	EventMessageEdit   EventType = "message.edit"          // *whats.IncomingMessage with target_id
+	EventMessageDelete EventType = "message.delete"        // *whats.IncomingMessage with target_id
+	EventReaction      EventType = "reaction"              // *whats.IncomingMessage with reaction
+	EventUndecryptable EventType = "message.undecryptable" // UndecryptablePayload
+	EventMessageStatus EventType = "message.status"        // receipts.Receipt
+	EventReceipt       EventType = "receipt"               // ReceiptPayload
+	EventPresence      EventType = "presence"              // PresencePayload
+	EventChatPresence  EventType = "chat_presence"         // ChatPresencePayload
+	EventGroup         EventType = "group"                 // GroupPayload
+	EventGroupJoined   EventType = "group.joined"          // GroupJoinedPayload
+	EventCall          EventType = "call"                  // CallPayload
+	EventHistorySync   EventType = "history_sync"          // HistorySyncPayload
+)
+
+// Event is the envelope of every message sent to WebSocket clients
+type Event struct {
+	Version int       `json:"v"`
+	Type    EventType `json:"type"`
+	ID      string    `json:"id"`
+	// Seq is the position in the event journal; clients resume after it
+	Seq       uint64    `json:"seq,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	UserID    string    `json:"user_id,omitempty"`
+	Payload   any       `json:"payload"`
+
+	scope scope
+}
+
+// NewEvent wraps payload in an envelope with a fresh id and the current time
+func NewEvent(t EventType, payload any) Event {
+	return Event{
+		Version:   EventVersion,
+		Type:      t,
+		ID:        newEventID(),
+		Timestamp: time.Now().UTC(),
+		Payload:   payload,
+		scope:     eventScope(payload),
+	}
+}
+
+func newEventID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// BotPayload reports the state of the bot process
+type BotPayload struct {
+	State string `json:"state"` // "started" or "stopping"
+}
+
+// ConnectionPayload reports a change of the WhatsApp connection
+type ConnectionPayload struct {
+	// State is one of connected, disconnected, logged_out, stream_replaced,
+	// temporary_ban, connect_failure, client_outdated, keepalive_timeout
+	// or keepalive_restored
+	State   string     `json:"state"`
+	Reason  string     `json:"reason,omitempty"`
+	Expires *time.Time `json:"expires,omitempty"` // End of a temporary ban
+}
+
+// QRPayload carries a code to render as a QR code and scan from the phone
+type QRPayload struct {
+	Code      string `json:"code"`
+	TimeoutMs int64  `json:"timeout_ms"`
+}
+
+// PairingPayload reports the outcome of a login
+type PairingPayload struct {
+	Status       string `json:"status"` // "success", "timeout" or "error"
+	JID          string `json:"jid,omitempty"`
+	Platform     string `json:"platform,omitempty"`
+	BusinessName string `json:"business_name,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+// SentPayload describes a message sent by the bot or through the REST API
+type SentPayload struct {
+	Message *whats.IncomingMessage `json:"message"`
+	Result  *senders.SendResult    `json:"result"`
+}
+
+// UndecryptablePayload describes a message that could not be decrypted
+type UndecryptablePayload struct {
+	ID            string    `json:"id"`
+	Chat          string    `json:"chat"`
+	Sender        string    `json:"sender"`
+	Timestamp     time.Time `json:"timestamp"`
+	IsGroup       bool      `json:"is_group"`
+	IsUnavailable bool      `json:"is_unavailable,omitempty"`
+}
+
+// ReceiptPayload is a delivery, read or played receipt for any message,
+// including ones sent from other devices of the account
+type ReceiptPayload struct {
+	Chat       string    `json:"chat"`
+	Sender     string    `json:"sender"`
+	IsGroup    bool      `json:"is_group"`
+	IsFromMe   bool      `json:"is_from_me"`
+	MessageIDs []string  `json:"message_ids"`
+	Type       string    `json:"type"` // "delivered", "read", "read-self", "played", ...
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// PresencePayload reports whether a contact is online
+type PresencePayload struct {
+	JID       string     `json:"jid"`
+	Available bool       `json:"available"`
+	LastSeen  *time.Time `json:"last_seen,omitempty"`
+}
+
+// ChatPresencePayload reports typing or recording in a chat
+type ChatPresencePayload struct {
+	Chat    string `json:"chat"`
+	Sender  string `json:"sender"`
+	IsGroup bool   `json:"is_group"`
+	State   string `json:"state"`           // "composing" or "paused"
+	Media   string `json:"media,omitempty"` // "audio" when recording a voice message
+}
+
+// GroupPayload describes a change of group metadata or membership. Only the
+// fields that changed are set.
+type GroupPayload struct {
+	JID       string    `json:"jid"`
+	Sender    string    `json:"sender,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	Name      *string   `json:"name,omitempty"`
+	Topic     *string   `json:"topic,omitempty"`
+	Locked    *bool     `json:"locked,omitempty"`
+	Announce  *bool     `json:"announce,omitempty"`
+	Join      []string  `json:"join,omitempty"`
+	Leave     []string  `json:"leave,omitempty"`
+	Promote   []string  `json:"promote,omitempty"`
+	Demote    []string  `json:"demote,omitempty"`
+}
+
+// GroupJoinedPayload reports that the account was added to a group
+type GroupJoinedPayload struct {
+	JID    string `json:"jid"`
+	Name   string `json:"name"`
+	Sender string `json:"sender,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// CallPayload describes an incoming call and its progress
+type CallPayload struct {
+	CallID    string    `json:"call_id"`
+	From      string    `json:"from"`
+	Timestamp time.Time `json:"timestamp"`
+	State     string    `json:"state"`           // "offer", "accept", "reject" or "terminate"
+	Media     string    `json:"media,omitempty"` // "audio" or "video", when known
+	GroupJID  string    `json:"group_jid,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+}
+
+// HistorySyncPayload reports a batch of chat history received after login
+type HistorySyncPayload struct {
+	Type          string `json:"type"`
+	Conversations int    `json:"conversations"`
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"fmt"
+	"regexp"
+
+	"go.mau.fi/whatsmeow/types"
+
+	"whatsappBotGo/src/internal/utils"
+	"whatsappBotGo/src/receipts"
+	"whatsappBotGo/src/whats"
+)
+
+// Filter selects the events a WebSocket client receives. Every set condition
+// must match; an empty filter matches every event.
+type Filter struct {
+	// Types lists the event types to receive
+	Types []EventType `json:"types,omitempty"`
+	// Chats lists chat JIDs or phone numbers; events outside a chat don't match
+	Chats []string `json:"chats,omitempty"`
+	// ChatType is "group" or "direct"; events outside a chat don't match
+	ChatType string `json:"chat_type,omitempty"`
+	// Text is a regular expression matched against message text and
+	// captions; events without text don't match
+	Text string `json:"text,omitempty"`
+}
+
+// matcher is a validated Filter
+type matcher struct {
+	types    map[EventType]bool
+	chats    map[string]bool
+	chatType string
+	text     *regexp.Regexp
+}
+
+// compile validates the filter
+func (f Filter) compile() (*matcher, error) {
+	m := &matcher{chatType: f.ChatType}
+	if len(f.Types) > 0 {
+		m.types = make(map[EventType]bool)
+		for _, t := range f.Types {
+			m.types[t] = true
+		}
+	}
+	if len(f.Chats) > 0 {
+		m.chats = make(map[string]bool)
+		for _, c := range f.Chats {
+			jid, err := utils.ParseUserJID(c)
+			if err != nil {
+				return nil, fmt.Errorf("invalid chat %q: %w", c, err)
+			}
+			m.chats[jid.String()] = true
+		}
+	}
+	switch f.ChatType {
+	case "", "group", "direct":
+	default:
+		return nil, fmt.Errorf("invalid chat_type %q: must be group or direct", f.ChatType)
+	}
+	if f.Text != "" {
+		re, err := regexp.Compile(f.Text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid text pattern: %w", err)
+		}
+		m.text = re
+	}
+	return m, nil
+}
+
+// scope is what filters look at besides the event type: the chat an event
+// belongs to, empty for events outside a chat, and its text
+type scope struct {
+	chat    string
+	isGroup bool
+	text    string
+}
+
+// match reports whether an event passes the filter; a nil matcher passes
+// everything
+func (m *matcher) match(t EventType, sc scope) bool {
+	if m == nil {
+		return true
+	}
+	if m.types != nil && !m.types[t] {
+		return false
+	}
+	if m.chats != nil && !m.chats[sc.chat] {
+		return false
+	}
+	if m.chatType != "" && (sc.chat == "" || sc.isGroup != (m.chatType == "group")) {
+		return false
+	}
+	if m.text != nil && (sc.text == "" || !m.text.MatchString(sc.text)) {
+		return false
+	}
+	return true
+}
+
+// eventScope returns the chat an event payload belongs to and its text
+func eventScope(payload any) scope {
+	switch p := payload.(type) {
+	case *whats.IncomingMessage:
+		if p != nil {
+			return scope{p.Chat.String(), p.IsGroup, p.Text}
+		}
+	case SentPayload:
+		if p.Message != nil {
+			return scope{p.Message.Chat.String(), p.Message.IsGroup, p.Message.Text}
+		}
+	case receipts.Receipt:
+		return scope{p.Chat, isGroupJID(p.Chat), ""}
+	case ReceiptPayload:
+		return scope{p.Chat, p.IsGroup, ""}
+	case UndecryptablePayload:
+		return scope{p.Chat, p.IsGroup, ""}
+	case PresencePayload:
+		return scope{p.JID, false, ""}
+	case ChatPresencePayload:
+		return scope{p.Chat, p.IsGroup, ""}
+	case GroupPayload:
+		return scope{p.JID, true, ""}
+	case GroupJoinedPayload:
+		return scope{p.JID, true, ""}
+	case CallPayload:
+		if p.GroupJID != "" {
+			return scope{p.GroupJID, true, ""}
+		}
+		return scope{p.From, false, ""}
+	}
+	return scope{}
+}
+
+func isGroupJID(jid string) bool {
+	parsed, err := utils.ParseUserJID(jid)
+	return err == nil && parsed.Server == types.GroupServer
+}
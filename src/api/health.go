@@ -2,8 +2,11 @@ package api
 
 import (
 	"encoding/json"
+	"log"
 	"net/http"
 	"time"
+
+	"whatsappBotGo/src/webhook"
 )
 
 // HealthResponse represents health check data
@@ -12,18 +15,31 @@ type HealthResponse struct {
 	Timestamp time.Time `json:"timestamp"`
 	Uptime    string    `json:"uptime"`
 	Version   string    `json:"version"`
+	WebSocket HubStats  `json:"websocket"`
+	// Webhooks is omitted when no webhook is configured
+	Webhooks *webhook.Stats `json:"webhooks,omitempty"`
 }
 
 // HandleHealth returns a health check response
 func (s *Server) HandleHealth(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(HealthResponse{
+	resp := HealthResponse{
 		Status:    "healthy",
 		Timestamp: time.Now(),
 		Uptime:    time.Since(s.startTime).String(),
 		Version:   "1.0.0",
-	})
+		WebSocket: s.Hub.Stats(),
+	}
+	if s.webhooks != nil {
+		if stats, err := s.webhooks.Stats(); err != nil {
+			log.Printf("Failed to read webhook stats: %v", err)
+		} else {
+			resp.Webhooks = &stats
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
 }
 
 // ControlResponse represents control command response
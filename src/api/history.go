@@ -0,0 +1,74 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"whatsappBotGo/src/history"
+	"whatsappBotGo/src/internal/utils"
+)
+
+// SetHistory enables the message history endpoints
+func (s *Server) SetHistory(store *history.Store) {
+	s.history = store
+}
+
+// chatMessagesHandler lists the transcript of one chat, newest first.
+// Query parameters: q (full-text search), cursor, limit.
+func (s *Server) chatMessagesHandler(w http.ResponseWriter, r *http.Request) {
+	jid, err := utils.ParseUserJID(r.PathValue("jid"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid jid"})
+		return
+	}
+	s.listMessages(w, r, history.Query{Chat: jid})
+}
+
+// searchMessagesHandler searches all chats; q is required
+func (s *Server) searchMessagesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("q") == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "q required"})
+		return
+	}
+	s.listMessages(w, r, history.Query{})
+}
+
+// listMessages completes q from the request query string and writes one page
+func (s *Server) listMessages(w http.ResponseWriter, r *http.Request, q history.Query) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if !s.checkQueryUserID(w, r) {
+		return
+	}
+	if s.history == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "message history not configured"})
+		return
+	}
+
+	params := r.URL.Query()
+	q.Search = params.Get("q")
+	q.Cursor = params.Get("cursor")
+	if limit := params.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid limit"})
+			return
+		}
+		q.Limit = n
+	}
+
+	page, err := s.history.List(q)
+	if errors.Is(err, history.ErrInvalidCursor) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid cursor"})
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("query failed: %v", err)})
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"whatsappBotGo/src/apikeys"
+)
+
+// CreateKeyRequest is the payload of POST /api/keys
+type CreateKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+// CreateKeyResponse returns a new key with its token, which is not shown
+// again
+type CreateKeyResponse struct {
+	Key   *apikeys.Key `json:"key"`
+	Token string       `json:"token"`
+}
+
+// keysHandler lists (GET) or creates (POST) API keys
+func (s *Server) keysHandler(w http.ResponseWriter, r *http.Request) {
+	if s.keys == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "api authentication disabled"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		keys, err := s.keys.List()
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("query failed: %v", err)})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"keys": keys})
+
+	case http.MethodPost:
+		var req CreateKeyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json payload"})
+			return
+		}
+		if strings.TrimSpace(req.Name) == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "name required"})
+			return
+		}
+		scopes, err := apikeys.ParseScopes(strings.Join(req.Scopes, ","))
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		key, token, err := s.keys.Create(req.Name, scopes)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("create failed: %v", err)})
+			return
+		}
+		writeJSON(w, http.StatusCreated, CreateKeyResponse{Key: key, Token: token})
+
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
+
+// keyHandler revokes a key (DELETE) and closes the WebSocket connections
+// opened with it
+func (s *Server) keyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if s.keys == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "api authentication disabled"})
+		return
+	}
+
+	id := r.PathValue("id")
+	err := s.keys.Revoke(id)
+	if errors.Is(err, apikeys.ErrNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "api key not found"})
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("revoke failed: %v", err)})
+		return
+	}
+	s.Hub.disconnectKey(id)
+	writeJSON(w, http.StatusOK, map[string]string{"status": "revoked"})
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+
+	"whatsappBotGo/src/receipts"
+	"whatsappBotGo/src/senders"
+	"whatsappBotGo/src/whats"
+)
+
+// WSMessage is the frame sent before events had an envelope. Clients that
+// connect with ?legacy=1 still get it after incoming message, message.sent
+// and message.status events until they move to the envelope.
+//
+// Deprecated: read the "payload" of the Event instead. Legacy frames will
+// be removed in the next release.
+type WSMessage struct {
+	From    string `json:"from"`
+	Text    string `json:"text,omitempty"`
+	Event   string `json:"event"`
+	RawType string `json:"raw_type,omitempty"`
+	UserID  string `json:"user_id,omitempty"`
+
+	// Message is the normalized incoming message, including media metadata
+	Message *whats.IncomingMessage `json:"message,omitempty"`
+	// Receipt is set on "receipt" events for messages sent by the bot
+	Receipt *receipts.Receipt `json:"receipt,omitempty"`
+	// Result is set on "sent" events
+	Result *senders.SendResult `json:"result,omitempty"`
+}
+
+// parseLegacy reads whether a connection asked for legacy frames
+func parseLegacy(q url.Values) (bool, error) {
+	if !q.Has("legacy") {
+		return false, nil
+	}
+	legacy, err := strconv.ParseBool(q.Get("legacy"))
+	if err != nil {
+		return false, fmt.Errorf("invalid legacy: %w", err)
+	}
+	return legacy, nil
+}
+
+// legacyFrame converts an event to its legacy frame, nil if there was none
+// for its type
+func legacyFrame(evt Event) *WSMessage {
+	switch p := evt.Payload.(type) {
+	case *whats.IncomingMessage:
+		if p == nil {
+			return nil
+		}
+		return &WSMessage{
+			From:    p.Sender.String(),
+			Text:    p.Text,
+			Event:   "message",
+			RawType: string(p.Type),
+			UserID:  evt.UserID,
+			Message: p,
+		}
+	case SentPayload:
+		if p.Message == nil {
+			return nil
+		}
+		return &WSMessage{
+			From:    p.Message.Sender.String(),
+			Text:    p.Message.Text,
+			Event:   "sent",
+			RawType: string(p.Message.Type),
+			UserID:  evt.UserID,
+			Message: p.Message,
+			Result:  p.Result,
+		}
+	case receipts.Receipt:
+		return &WSMessage{
+			From:    p.Participant,
+			Event:   "receipt",
+			RawType: string(p.Status),
+			UserID:  evt.UserID,
+			Receipt: &p,
+		}
+	}
+	return nil
+}
+
+// legacyData encodes the legacy frame of an event, nil if it has none
+func legacyData(msg Event) []byte {
+	frame := legacyFrame(msg)
+	if frame == nil {
+		return nil
+	}
+	data, err := json.Marshal(frame)
+	if err != nil {
+		log.Printf("Failed to encode legacy %s frame: %v", msg.Type, err)
+		return nil
+	}
+	return data
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"whatsappBotGo/src/functions"
+)
+
+// LocalFilesConfig controls sending files from the server's disk through
+// the file field of the send requests
+type LocalFilesConfig struct {
+	Enabled bool
+	Root    string // Only files below this directory can be sent
+}
+
+// LocalFilesConfigFromEnv reads API_LOCAL_FILES and API_MEDIA_ROOT
+func LocalFilesConfigFromEnv() LocalFilesConfig {
+	return LocalFilesConfig{
+		Enabled: functions.GetEnvBool("API_LOCAL_FILES", false),
+		Root:    functions.GetEnv("API_MEDIA_ROOT", "./outgoing"),
+	}
+}
+
+// mediaRoot is the directory local files are sent from
+type mediaRoot struct {
+	abs  string // As configured, made absolute
+	real string // With symlinks resolved
+}
+
+// newMediaRoot creates the root if needed and resolves it. It returns nil
+// when local files are disabled or the root is unusable.
+func newMediaRoot(cfg LocalFilesConfig) *mediaRoot {
+	if !cfg.Enabled {
+		return nil
+	}
+	abs, err := filepath.Abs(cfg.Root)
+	if err == nil {
+		err = os.MkdirAll(abs, 0o755)
+	}
+	var real string
+	if err == nil {
+		real, err = filepath.EvalSymlinks(abs)
+	}
+	if err != nil {
+		log.Printf("Local files disabled, invalid API_MEDIA_ROOT %q: %v", cfg.Root, err)
+		return nil
+	}
+	return &mediaRoot{abs: abs, real: real}
+}
+
+// contains reports whether path lies below dir; both must be clean and
+// absolute
+func contains(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// localFile resolves the file field of a send request to a regular file
+// inside the media root. Relative names are relative to the root. The path
+// is checked before and after resolving symlinks, so neither ".." nor a
+// link can reach files such as session.db outside of it.
+func (s *Server) localFile(name string) (string, error) {
+	if s.mediaRoot == nil {
+		return "", &apiError{Status: http.StatusForbidden, Message: "local files are disabled; upload the file or send it by url instead"}
+	}
+
+	path := filepath.Clean(name)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(s.mediaRoot.real, path)
+	}
+	// Reject paths outside the root before touching the disk, so callers
+	// can't probe which files exist elsewhere
+	if !contains(s.mediaRoot.real, path) && !contains(s.mediaRoot.abs, path) {
+		return "", &apiError{Status: http.StatusForbidden, Message: "file is outside the media root"}
+	}
+
+	real, err := filepath.EvalSymlinks(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", &apiError{Status: http.StatusBadRequest, Message: "file not found"}
+	}
+	if err != nil {
+		return "", &apiError{Status: http.StatusBadRequest, Message: fmt.Sprintf("invalid file: %v", err)}
+	}
+	if !contains(s.mediaRoot.real, real) {
+		return "", &apiError{Status: http.StatusForbidden, Message: "file links outside the media root"}
+	}
+
+	info, err := os.Stat(real)
+	if err != nil {
+		return "", &apiError{Status: http.StatusBadRequest, Message: fmt.Sprintf("invalid file: %v", err)}
+	}
+	if !info.Mode().IsRegular() {
+		return "", &apiError{Status: http.StatusBadRequest, Message: "file is not a regular file"}
+	}
+	return real, nil
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"whatsappBotGo/src/media"
+)
+
+// SetMediaStore enables GET /api/media/{id} for downloaded attachments
+func (s *Server) SetMediaStore(store *media.Store) {
+	s.media = store
+}
+
+// checkQueryUserID validates the user_id query parameter of GET requests,
+// which have no body to carry it, and writes an error if it doesn't match
+func (s *Server) checkQueryUserID(w http.ResponseWriter, r *http.Request) bool {
+	if err := s.checkUserID(r.URL.Query().Get("user_id")); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return false
+	}
+	return true
+}
+
+// inlineMedia reports whether a stored file may be displayed by browsers:
+// images, video and audio, except SVG, which can run scripts
+func inlineMedia(mimetype string) bool {
+	base, _, _ := strings.Cut(mimetype, ";")
+	base = strings.ToLower(strings.TrimSpace(base))
+	if base == "image/svg+xml" {
+		return false
+	}
+	return strings.HasPrefix(base, "image/") || strings.HasPrefix(base, "video/") || strings.HasPrefix(base, "audio/")
+}
+
+// mediaHandler serves a stored attachment by the id given in WS events
+func (s *Server) mediaHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	if !s.checkQueryUserID(w, r) {
+		return
+	}
+
+	if s.media == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "media store not configured"})
+		return
+	}
+
+	f, info, err := s.media.Open(r.PathValue("id"))
+	if errors.Is(err, media.ErrNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "media not found"})
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("open failed: %v", err)})
+		return
+	}
+	defer f.Close()
+
+	// The mimetype is whatever the remote sender declared, so browsers must
+	// not sniff a different one and only plain media is shown inline
+	mimetype := info.Mimetype
+	if mimetype == "" {
+		mimetype = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", mimetype)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	disposition := "attachment"
+	if inlineMedia(mimetype) {
+		disposition = "inline"
+	}
+	params := map[string]string{}
+	if info.FileName != "" {
+		params["filename"] = info.FileName
+	}
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, params))
+	// The id is the content hash, so the file behind it never changes
+	w.Header().Set("ETag", `"`+info.ID+`"`)
+	w.Header().Set("Cache-Control", "private, max-age=31536000, immutable")
+	http.ServeContent(w, r, "", info.CreatedAt, f)
+}
@@ -1,5 +1,7 @@
 package api
 
+import "whatsappBotGo/src/senders"
+
 // JSON models for API requests
 
 type SendTextRequest struct {
@@ -9,21 +11,43 @@ type SendTextRequest struct {
 }
 
 type SendMediaRequest struct {
-	JID     string `json:"jid"`
-	URL     string `json:"url,omitempty"`  // if provided, server will download
-	File    string `json:"file,omitempty"` // local file path
-	Caption string `json:"caption,omitempty"`
-	Title   string `json:"title,omitempty"` // for documents
-	UserID  string `json:"user_id,omitempty"`
+	JID      string `json:"jid"`
+	URL      string `json:"url,omitempty"`  // if provided, server will download
+	File     string `json:"file,omitempty"` // local file path
+	Caption  string `json:"caption,omitempty"`
+	Title    string `json:"title,omitempty"`     // for documents
+	QuotedID string `json:"quoted_id,omitempty"` // ID of a message in the chat to reply to
+	Mimetype string `json:"mimetype,omitempty"`  // overrides the sniffed type
+	FileName string `json:"file_name,omitempty"` // shown for documents
+	UserID   string `json:"user_id,omitempty"`
+}
+
+type ReactRequest struct {
+	Chat      string `json:"chat"`
+	Sender    string `json:"sender"` // Author of the message reacted to
+	MessageID string `json:"message_id"`
+	Emoji     string `json:"emoji"` // Empty removes the reaction
+	UserID    string `json:"user_id,omitempty"`
+}
+
+type MarkReadRequest struct {
+	Chat       string   `json:"chat"`
+	Sender     string   `json:"sender,omitempty"` // Author of the messages, required in groups
+	MessageIDs []string `json:"message_ids"`
+	UserID     string   `json:"user_id,omitempty"`
+}
+
+type TypingRequest struct {
+	JID    string `json:"jid"`
+	State  string `json:"state"` // "typing", "recording" or "paused"
+	UserID string `json:"user_id,omitempty"`
 }
 
-// Message struct broadcasted over WebSocket
-type WSMessage struct {
-	From    string `json:"from"`
-	Text    string `json:"text,omitempty"`
-	Event   string `json:"event"`
-	RawType string `json:"raw_type,omitempty"`
-	UserID  string `json:"user_id,omitempty"`
+// SendResponse is returned by the send endpoints. It embeds the send result
+// so message_id, timestamp and recipient sit next to status.
+type SendResponse struct {
+	Status string `json:"status"`
+	*senders.SendResult
 }
 
 // Helper to convert string JID to types.JID is done in handlers
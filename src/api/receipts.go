@@ -0,0 +1,41 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"whatsappBotGo/src/receipts"
+)
+
+// SetReceipts enables GET /api/messages/{id}/status
+func (s *Server) SetReceipts(tracker *receipts.Tracker) {
+	s.receipts = tracker
+}
+
+// messageStatusHandler returns the delivery state of a message sent by the
+// bot, overall and per recipient
+func (s *Server) messageStatusHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if !s.checkQueryUserID(w, r) {
+		return
+	}
+	if s.receipts == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "receipt tracking not configured"})
+		return
+	}
+
+	status, err := s.receipts.Status(r.PathValue("id"))
+	if errors.Is(err, receipts.ErrNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "message not found"})
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("query failed: %v", err)})
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"whatsappBotGo/src/journal"
+)
+
+// replayPage is how many journal entries are read at a time
+const replayPage = 500
+
+// replay is the part of the journal a resuming client missed
+type replay struct {
+	since uint64 // Last event the client received
+	until uint64 // Last event before the client connected
+}
+
+// ReplayFrame brackets the events replayed to a client that connected with
+// ?since=<seq>
+type ReplayFrame struct {
+	Version int    `json:"v"`
+	Type    string `json:"type"` // "replay.start" or "replay.done"
+	Since   uint64 `json:"since"`
+	Until   uint64 `json:"until"` // Live events start after this sequence number
+	// Oldest is the oldest event still in the journal
+	Oldest uint64 `json:"oldest,omitempty"`
+	// Evicted is true when events after since were already removed from
+	// the journal; the replay starts at Oldest and the events in between
+	// are lost
+	Evicted bool `json:"evicted,omitempty"`
+	// Reset is true when since is ahead of the journal, e.g. because it was
+	// deleted; the cursor is stale and the whole journal is replayed
+	Reset bool `json:"reset,omitempty"`
+}
+
+// SetJournal records broadcast events so clients can resume with ?since=<seq>
+func (s *Server) SetJournal(store *journal.Store) {
+	s.Hub.journal = store
+}
+
+// record assigns the event its sequence number, stores it in the journal
+// and returns it encoded. Without a journal the event is only encoded. On a
+// journal error the encoded event is still returned for live clients.
+func (h *Hub) record(msg *Event) ([]byte, error) {
+	if h.journal == nil {
+		return json.Marshal(msg)
+	}
+	entry := journal.Entry{Type: string(msg.Type), Chat: msg.scope.chat, IsGroup: msg.scope.isGroup, Text: msg.scope.text}
+	entry, err := h.journal.Append(entry, func(seq uint64) ([]byte, error) {
+		msg.Seq = seq
+		return json.Marshal(msg)
+	})
+	return entry.Data, err
+}
+
+// parseSince reads the resume cursor of a connection
+func (h *Hub) parseSince(q url.Values) (since uint64, ok bool, err error) {
+	if !q.Has("since") {
+		return 0, false, nil
+	}
+	if h.journal == nil {
+		return 0, false, errors.New("event journal is disabled")
+	}
+	since, err = strconv.ParseUint(q.Get("since"), 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid since: %w", err)
+	}
+	return since, true, nil
+}
+
+// filterFromQuery reads a subscription filter from query parameters; lists
+// are comma-separated
+func filterFromQuery(q url.Values) Filter {
+	list := func(key string) []string {
+		var items []string
+		for _, item := range strings.Split(q.Get(key), ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		return items
+	}
+
+	f := Filter{Chats: list("chats"), ChatType: q.Get("chat_type"), Text: q.Get("text")}
+	for _, t := range list("types") {
+		f.Types = append(f.Types, EventType(t))
+	}
+	return f
+}
+
+// replay sends the journal entries a resuming client missed, bracketed by
+// replay frames, before its live events. Live events are held meanwhile.
+func (h *Hub) replay(cl *client) error {
+	r := cl.replay
+	oldest, err := h.journal.Oldest()
+	if err != nil {
+		return err
+	}
+	start := ReplayFrame{Type: "replay.start", Since: r.since, Until: r.until, Oldest: oldest}
+	after := r.since
+	if r.since > r.until {
+		start.Reset = true
+		after = 0
+	}
+	start.Evicted = oldest > after+1 && after < r.until
+	if err := h.writeFrame(cl, start); err != nil {
+		return err
+	}
+
+	for after < r.until {
+		entries, err := h.journal.Range(after, r.until, replayPage)
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			break
+		}
+		h.mu.RLock()
+		filter := cl.filter
+		h.mu.RUnlock()
+		for _, e := range entries {
+			after = e.Seq
+			if !filter.match(EventType(e.Type), scope{chat: e.Chat, isGroup: e.IsGroup, text: e.Text}) {
+				continue
+			}
+			if err := h.write(cl, e.Data); err != nil {
+				return err
+			}
+		}
+	}
+
+	return h.writeFrame(cl, ReplayFrame{Type: "replay.done", Since: r.since, Until: r.until})
+}
+
+// writeFrame encodes and writes a frame directly, bypassing the queue
+func (h *Hub) writeFrame(cl *client, frame ReplayFrame) error {
+	frame.Version = EventVersion
+	data, err := json.Marshal(frame)
+	if err != nil {
+		return err
+	}
+	return h.write(cl, data)
+}
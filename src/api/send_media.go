@@ -0,0 +1,247 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	waE2E "go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+
+	"whatsappBotGo/src/fetch"
+	"whatsappBotGo/src/history"
+	"whatsappBotGo/src/senders"
+)
+
+// mediaKinds are served as /api/send/<kind> and send_<kind> WebSocket
+// actions. A new kind such as audio or sticker needs an entry here and a
+// case in mediaSender.
+var mediaKinds = []fetch.Kind{fetch.KindImage, fetch.KindVideo, fetch.KindDocument}
+
+// mediaSend sends a file of one media kind
+type mediaSend func(to types.JID, path string, req SendMediaRequest, opts senders.MediaOptions) (*senders.SendResult, error)
+
+// mediaSender returns the sender of kind, nil if it is not configured
+func (s *Server) mediaSender(kind fetch.Kind) mediaSend {
+	switch {
+	case s.senders == nil:
+	case kind == fetch.KindImage && s.senders.Image != nil:
+		return func(to types.JID, path string, req SendMediaRequest, opts senders.MediaOptions) (*senders.SendResult, error) {
+			return s.senders.Image.SendImageWithOptions(to, path, req.Caption, opts)
+		}
+	case kind == fetch.KindVideo && s.senders.Video != nil:
+		return func(to types.JID, path string, req SendMediaRequest, opts senders.MediaOptions) (*senders.SendResult, error) {
+			return s.senders.Video.SendVideoWithOptions(to, path, req.Caption, opts)
+		}
+	case kind == fetch.KindDocument && s.senders.Document != nil:
+		return func(to types.JID, path string, req SendMediaRequest, opts senders.MediaOptions) (*senders.SendResult, error) {
+			return s.senders.Document.SendDocumentWithOptions(to, path, req.Title, opts)
+		}
+	}
+	return nil
+}
+
+// sendMediaHandler serves /api/send/<kind>. The body is either a JSON
+// SendMediaRequest or multipart/form-data with the same fields and the
+// content in a "file" part.
+func (s *Server) sendMediaHandler(kind fetch.Kind) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+			return
+		}
+
+		var req SendMediaRequest
+		var up *upload
+		if mediaType, _, _ := strings.Cut(r.Header.Get("Content-Type"), ";"); strings.TrimSpace(mediaType) == "multipart/form-data" {
+			var err error
+			if up, err = s.readMultipart(w, r, kind, &req); up != nil {
+				defer os.Remove(up.path)
+			}
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+		} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json payload"})
+			return
+		}
+
+		result, err := s.sendMedia(kind, req, up)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, SendResponse{Status: "ok", SendResult: result})
+	}
+}
+
+// sendMedia validates a media request and sends the uploaded file, the file
+// from the media root or the downloaded url
+func (s *Server) sendMedia(kind fetch.Kind, req SendMediaRequest, up *upload) (*senders.SendResult, error) {
+	if err := s.checkUserID(req.UserID); err != nil {
+		return nil, err
+	}
+	jid, err := types.ParseJID(req.JID)
+	if err != nil {
+		return nil, &apiError{Status: http.StatusBadRequest, Message: "invalid jid"}
+	}
+	send := s.mediaSender(kind)
+	if send == nil {
+		return nil, &apiError{Status: http.StatusServiceUnavailable, Message: fmt.Sprintf("%s sender not configured", kind)}
+	}
+	if err := checkMimetype(kind, req.Mimetype); err != nil {
+		return nil, err
+	}
+	quoted, err := s.quotedMessage(jid, req.QuotedID)
+	if err != nil {
+		return nil, err
+	}
+	opts := senders.MediaOptions{Quoted: quoted, Mimetype: req.Mimetype}
+	if req.FileName != "" {
+		opts.FileName = filepath.Base(filepath.Clean("/" + req.FileName))
+	}
+
+	var file string
+	if up != nil {
+		if req.File != "" || req.URL != "" {
+			return nil, &apiError{Status: http.StatusBadRequest, Message: "send either an uploaded file, url or file"}
+		}
+		file = up.path
+		if req.FileName == "" {
+			opts.FileName = up.fileName
+		}
+	} else {
+		var cleanup func()
+		if file, cleanup, err = s.mediaPath(kind, req); err != nil {
+			return nil, err
+		}
+		defer cleanup()
+		if req.FileName == "" {
+			opts.FileName = sourceName(req)
+		}
+	}
+
+	result, err := send(jid, file, req, opts)
+	if err != nil {
+		return nil, fmt.Errorf("send failed: %v", err)
+	}
+	return result, nil
+}
+
+// checkMimetype validates a mimetype override, which must still be of the
+// requested kind
+func checkMimetype(kind fetch.Kind, mimetype string) error {
+	if mimetype == "" {
+		return nil
+	}
+	parsed, _, err := mime.ParseMediaType(mimetype)
+	if err != nil {
+		return &apiError{Status: http.StatusBadRequest, Message: "invalid mimetype"}
+	}
+	if kind != fetch.KindDocument && !strings.HasPrefix(parsed, string(kind)+"/") {
+		return &apiError{Status: http.StatusBadRequest, Message: fmt.Sprintf("mimetype %s doesn't match the %s kind", parsed, kind)}
+	}
+	return nil
+}
+
+// sourceName names a file sent from the media root or a url after its last
+// path element, so its extension is kept
+func sourceName(req SendMediaRequest) string {
+	if req.File != "" {
+		return filepath.Base(req.File)
+	}
+	u, err := url.Parse(req.URL)
+	if err != nil {
+		return ""
+	}
+	if name := path.Base(u.Path); name != "/" && name != "." {
+		return name
+	}
+	return ""
+}
+
+// quotedMessage looks up the message a send replies to in the history. Only
+// its text is quoted, which is what the reply preview shows.
+func (s *Server) quotedMessage(chat types.JID, id string) (*senders.QuotedMessage, error) {
+	if id == "" {
+		return nil, nil
+	}
+	if s.history == nil {
+		return nil, &apiError{Status: http.StatusServiceUnavailable, Message: "message history not configured, can't quote"}
+	}
+	msg, err := s.history.Get(chat, id)
+	if errors.Is(err, history.ErrNotFound) {
+		return nil, &apiError{Status: http.StatusBadRequest, Message: "quoted message not found"}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if msg.Deleted {
+		return nil, &apiError{Status: http.StatusBadRequest, Message: "quoted message was deleted"}
+	}
+	sender, err := types.ParseJID(msg.Sender)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender of quoted message: %w", err)
+	}
+	return &senders.QuotedMessage{
+		MessageID: msg.ID,
+		Sender:    sender,
+		Message:   &waE2E.Message{Conversation: proto.String(msg.Text)},
+	}, nil
+}
+
+// mediaPath returns the file to send for a media request: a file from the
+// media root, or the url downloaded to a temp file. cleanup removes the
+// download once it is sent.
+func (s *Server) mediaPath(kind fetch.Kind, req SendMediaRequest) (path string, cleanup func(), err error) {
+	cleanup = func() {}
+	switch {
+	case req.File != "":
+		path, err = s.localFile(req.File)
+		return path, cleanup, err
+	case req.URL != "":
+		result, err := s.fetcher.Fetch(context.Background(), req.URL, kind, s.TempDir)
+		if err != nil {
+			return "", cleanup, fetchError(err)
+		}
+		return result.Path, func() { os.Remove(result.Path) }, nil
+	}
+	return "", cleanup, &apiError{Status: http.StatusBadRequest, Message: "file or url required"}
+}
+
+// fetchStatus is the HTTP status reported for each reason a download fails
+var fetchStatus = map[fetch.Reason]int{
+	fetch.ReasonInvalidURL:   http.StatusBadRequest,
+	fetch.ReasonScheme:       http.StatusBadRequest,
+	fetch.ReasonRedirects:    http.StatusBadRequest,
+	fetch.ReasonBlocked:      http.StatusForbidden,
+	fetch.ReasonTooLarge:     http.StatusRequestEntityTooLarge,
+	fetch.ReasonWrongType:    http.StatusUnsupportedMediaType,
+	fetch.ReasonStatus:       http.StatusBadGateway,
+	fetch.ReasonUnreachable:  http.StatusBadGateway,
+	fetch.ReasonTimeout:      http.StatusGatewayTimeout,
+	fetch.ReasonStorageError: http.StatusInternalServerError,
+}
+
+// fetchError reports a failed download with a status matching its reason
+func fetchError(err error) error {
+	var fe *fetch.Error
+	if !errors.As(err, &fe) {
+		return fmt.Errorf("download failed: %v", err)
+	}
+	status, ok := fetchStatus[fe.Reason]
+	if !ok {
+		status = http.StatusBadGateway
+	}
+	return &apiError{Status: status, Message: fe.Message}
+}
@@ -3,14 +3,21 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"os"
 	"time"
 
+	"whatsappBotGo/src/apikeys"
+	"whatsappBotGo/src/fetch"
+	"whatsappBotGo/src/functions"
+	"whatsappBotGo/src/history"
+	"whatsappBotGo/src/media"
+	"whatsappBotGo/src/receipts"
 	"whatsappBotGo/src/senders"
+	"whatsappBotGo/src/webhook"
 
 	"go.mau.fi/whatsmeow/types"
 )
@@ -24,10 +31,18 @@ type Server struct {
 	InstanceUserID string
 	startTime      time.Time
 	stopChan       chan bool
+	media          *media.Store
+	history        *history.Store
+	receipts       *receipts.Tracker
+	webhooks       *webhook.Outbox
+	keys           *apikeys.Store
+	mediaRoot      *mediaRoot // Nil if local files are disabled
+	fetcher        *fetch.Fetcher
+	uploadLimit    int64 // Largest file in a multipart request, in bytes
 }
 
 func NewServer(s *senders.Senders, instanceUserID string) *Server {
-	hub := NewHub()
+	hub := NewHub(HubConfigFromEnv())
 	addr := os.Getenv("API_ADDR")
 	if addr == "" {
 		addr = ":8080"
@@ -39,24 +54,45 @@ func NewServer(s *senders.Senders, instanceUserID string) *Server {
 		InstanceUserID: instanceUserID,
 		startTime:      time.Now(),
 		stopChan:       make(chan bool, 1),
+		mediaRoot:      newMediaRoot(LocalFilesConfigFromEnv()),
+		fetcher:        fetch.New(fetch.ConfigFromEnv()),
+		uploadLimit:    int64(functions.GetEnvInt("API_UPLOAD_MAX_MB", 64)) << 20,
 	}
 	mux := http.NewServeMux()
 
-	mux.HandleFunc("/api/send/text", srv.sendTextHandler)
-	mux.HandleFunc("/api/send/image", srv.sendImageHandler)
-	mux.HandleFunc("/api/send/video", srv.sendVideoHandler)
-	mux.HandleFunc("/api/send/document", srv.sendDocumentHandler)
-	mux.HandleFunc("/ws", hub.ServeWS)
-	mux.HandleFunc("/api/health", srv.HandleHealth)
-	mux.HandleFunc("/api/control/status", srv.HandleControlStatus)
-	mux.HandleFunc("/api/control/stop", srv.HandleControlStop)
+	// Every endpoint requires an API key with the given scope once
+	// SetAPIKeys is called; admin keys may use all of them
+	send, read, admin := apikeys.ScopeSend, apikeys.ScopeRead, apikeys.ScopeAdmin
+	mux.HandleFunc("/api/send/text", srv.require(send, srv.sendTextHandler))
+	for _, kind := range mediaKinds {
+		mux.HandleFunc("/api/send/"+string(kind), srv.require(send, srv.sendMediaHandler(kind)))
+	}
+	mux.HandleFunc("/api/media/{id}", srv.require(read, srv.mediaHandler))
+	mux.HandleFunc("/api/chats/{jid}/messages", srv.require(read, srv.chatMessagesHandler))
+	mux.HandleFunc("/api/messages/search", srv.require(read, srv.searchMessagesHandler))
+	mux.HandleFunc("/api/messages/{id}/status", srv.require(read, srv.messageStatusHandler))
+	mux.HandleFunc("/api/webhooks/dead", srv.require(admin, srv.deadWebhooksHandler))
+	mux.HandleFunc("/api/webhooks/dead/replay", srv.require(admin, srv.replayDeadWebhooksHandler))
+	mux.HandleFunc("/api/webhooks/dead/{id}", srv.require(admin, srv.deadWebhookHandler))
+	mux.HandleFunc("/api/webhooks/dead/{id}/replay", srv.require(admin, srv.replayDeadWebhookHandler))
+	mux.HandleFunc("/api/keys", srv.require(admin, srv.keysHandler))
+	mux.HandleFunc("/api/keys/{id}", srv.require(admin, srv.keyHandler))
+	mux.HandleFunc("/ws", srv.require(apikeys.ScopeEvents, hub.ServeWS))
+	if functions.GetEnvBool("API_PUBLIC_HEALTH", false) {
+		mux.HandleFunc("/api/health", srv.HandleHealth)
+	} else {
+		mux.HandleFunc("/api/health", srv.require(read, srv.HandleHealth))
+	}
+	mux.HandleFunc("/api/control/status", srv.require(read, srv.HandleControlStatus))
+	mux.HandleFunc("/api/control/stop", srv.require(admin, srv.HandleControlStop))
+
+	srv.registerActions()
 
 	srv.httpServer = &http.Server{Addr: addr, Handler: mux}
 	return srv
 }
 
 func (s *Server) Start() {
-	go s.Hub.Run()
 	log.Printf("Starting API server on %s", s.httpServer.Addr)
 	go func() {
 		if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -66,6 +102,8 @@ func (s *Server) Start() {
 }
 
 func (s *Server) Shutdown(ctx context.Context) error {
+	// Hijacked websocket connections are not closed by http.Server
+	s.Hub.Close()
 	return s.httpServer.Shutdown(ctx)
 }
 
@@ -76,139 +114,41 @@ func writeJSON(w http.ResponseWriter, status int, payload any) {
 	json.NewEncoder(w).Encode(payload)
 }
 
-func (s *Server) sendTextHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
-		return
-	}
-	var req SendTextRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json payload"})
-		return
-	}
-
-	// Validate user id: if server has an instance bound, ensure the request matches
-	if s.InstanceUserID != "" {
-		if req.UserID == "" {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "user_id required"})
-			return
-		}
-		if req.UserID != s.InstanceUserID {
-			writeJSON(w, http.StatusForbidden, map[string]string{"error": "user_id mismatch"})
-			return
-		}
-	}
-
-	jid, err := types.ParseJID(req.JID)
-	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid jid"})
-		return
-	}
-
-	if s.senders == nil || s.senders.Text == nil {
-		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "text sender not configured"})
-		return
-	}
-
-	if err := s.senders.Text.SendText(jid, req.Text); err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("send failed: %v", err)})
-		return
-	}
-	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+// apiError is an error reported to clients with its own HTTP status
+type apiError struct {
+	Status  int
+	Message string
 }
 
-// downloadFile downloads a remote file to the tempdir and returns path
-func (s *Server) downloadFile(url string) (string, error) {
-	client := http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Get(url)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	tmpFile, err := os.CreateTemp(s.TempDir, "api_media_*")
-	if err != nil {
-		return "", err
-	}
-	defer tmpFile.Close()
-
-	if _, err = io.Copy(tmpFile, resp.Body); err != nil {
-		return "", err
-	}
-	return tmpFile.Name(), nil
+func (e *apiError) Error() string {
+	return e.Message
 }
 
-func (s *Server) sendImageHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
-		return
-	}
-	var req SendMediaRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json payload"})
-		return
-	}
-
-	// Validate user id: if server has an instance bound, ensure the request matches
-	if s.InstanceUserID != "" {
-		if req.UserID == "" {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "user_id required"})
-			return
-		}
-		if req.UserID != s.InstanceUserID {
-			writeJSON(w, http.StatusForbidden, map[string]string{"error": "user_id mismatch"})
-			return
-		}
-	}
-
-	jid, err := types.ParseJID(req.JID)
-	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid jid"})
-		return
-	}
-	if s.senders == nil || s.senders.Image == nil {
-		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "image sender not configured"})
-		return
-	}
-
-	path := req.File
-	if path == "" && req.URL != "" {
-		path, err = s.downloadFile(req.URL)
-		if err != nil {
-			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("download failed: %v", err)})
-			return
-		}
-		defer os.Remove(path)
-	}
-
-	if err := s.senders.Image.SendImage(jid, path, req.Caption); err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("send failed: %v", err)})
-		return
+// writeError writes err as an error response, with the status of an
+// apiError or else the given one
+func writeError(w http.ResponseWriter, status int, err error) {
+	var ae *apiError
+	if errors.As(err, &ae) {
+		status = ae.Status
 	}
-	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	writeJSON(w, status, map[string]string{"error": err.Error()})
 }
 
-func (s *Server) sendVideoHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) sendTextHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
 		return
 	}
-	var req SendMediaRequest
+	var req SendTextRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json payload"})
 		return
 	}
 
 	// Validate user id: if server has an instance bound, ensure the request matches
-	if s.InstanceUserID != "" {
-		if req.UserID == "" {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "user_id required"})
-			return
-		}
-		if req.UserID != s.InstanceUserID {
-			writeJSON(w, http.StatusForbidden, map[string]string{"error": "user_id mismatch"})
-			return
-		}
+	if err := s.checkUserID(req.UserID); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
 	}
 
 	jid, err := types.ParseJID(req.JID)
@@ -216,84 +156,32 @@ func (s *Server) sendVideoHandler(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid jid"})
 		return
 	}
-	if s.senders == nil || s.senders.Video == nil {
-		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "video sender not configured"})
-		return
-	}
 
-	path := req.File
-	if path == "" && req.URL != "" {
-		path, err = s.downloadFile(req.URL)
-		if err != nil {
-			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("download failed: %v", err)})
-			return
-		}
-		defer os.Remove(path)
-	}
-
-	if err := s.senders.Video.SendVideo(jid, path, req.Caption); err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("send failed: %v", err)})
-		return
-	}
-	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
-}
-
-func (s *Server) sendDocumentHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
-		return
-	}
-	var req SendMediaRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json payload"})
+	if s.senders == nil || s.senders.Text == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "text sender not configured"})
 		return
 	}
 
-	// Validate user id: if server has an instance bound, ensure the request matches
-	if s.InstanceUserID != "" {
-		if req.UserID == "" {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "user_id required"})
-			return
-		}
-		if req.UserID != s.InstanceUserID {
-			writeJSON(w, http.StatusForbidden, map[string]string{"error": "user_id mismatch"})
-			return
-		}
-	}
-
-	jid, err := types.ParseJID(req.JID)
+	result, err := s.senders.Text.SendText(jid, req.Text)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid jid"})
-		return
-	}
-	if s.senders == nil || s.senders.Document == nil {
-		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "document sender not configured"})
-		return
-	}
-
-	path := req.File
-	if path == "" && req.URL != "" {
-		path, err = s.downloadFile(req.URL)
-		if err != nil {
-			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("download failed: %v", err)})
-			return
-		}
-		defer os.Remove(path)
-	}
-
-	if err := s.senders.Document.SendDocument(jid, path, req.Title); err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("send failed: %v", err)})
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	writeJSON(w, http.StatusOK, SendResponse{Status: "ok", SendResult: result})
 }
 
-func (s *Server) BroadcastIncoming(msg WSMessage) {
+// Publish broadcasts an event to WebSocket clients and queues it for the
+// webhooks
+func (s *Server) Publish(t EventType, payload any) {
 	if s.Hub != nil {
+		evt := NewEvent(t, payload)
 		// annotate with instance user id if available
-		if s.InstanceUserID != "" {
-			msg.UserID = s.InstanceUserID
+		evt.UserID = s.InstanceUserID
+		data := s.Hub.Broadcast(evt)
+		if s.webhooks != nil && data != nil {
+			if err := s.webhooks.Enqueue(evt.ID, string(evt.Type), data); err != nil {
+				log.Printf("Failed to queue %s event for webhooks: %v", evt.Type, err)
+			}
 		}
-		s.Hub.Broadcast(msg)
 	}
 }
@@ -0,0 +1,150 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"whatsappBotGo/src/fetch"
+)
+
+// maxFieldSize limits the text fields of a multipart request
+const maxFieldSize = 64 << 10
+
+// upload is a file received in a multipart request
+type upload struct {
+	path     string // Temp file; the handler removes it
+	fileName string // As named by the client, without directories
+	size     int64
+}
+
+// readMultipart reads the fields of a multipart send request into req and
+// streams its "file" part to a temp file, so large files are never held in
+// memory. Fields may come before or after the file. The returned upload is
+// set whenever a temp file was created, even on error.
+func (s *Server) readMultipart(w http.ResponseWriter, r *http.Request, kind fetch.Kind, req *SendMediaRequest) (*upload, error) {
+	limit := s.uploadLimit
+	// Leave room for the fields and part headers around the file
+	r.Body = http.MaxBytesReader(w, r.Body, limit+1<<20)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, &apiError{Status: http.StatusBadRequest, Message: "invalid multipart body"}
+	}
+
+	fields := map[string]*string{
+		"jid":       &req.JID,
+		"caption":   &req.Caption,
+		"title":     &req.Title,
+		"url":       &req.URL,
+		"file":      &req.File,
+		"quoted_id": &req.QuotedID,
+		"mimetype":  &req.Mimetype,
+		"file_name": &req.FileName,
+		"user_id":   &req.UserID,
+	}
+
+	var up *upload
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return up, bodyError(err)
+		}
+
+		if part.FormName() == "file" && part.FileName() != "" {
+			if up != nil {
+				return up, &apiError{Status: http.StatusBadRequest, Message: "only one file can be uploaded"}
+			}
+			if up, err = s.saveUpload(part, kind, limit); err != nil {
+				return up, err
+			}
+			continue
+		}
+
+		field, ok := fields[part.FormName()]
+		if !ok {
+			continue
+		}
+		value, err := io.ReadAll(io.LimitReader(part, maxFieldSize+1))
+		if err != nil {
+			return up, bodyError(err)
+		}
+		if len(value) > maxFieldSize {
+			return up, &apiError{Status: http.StatusRequestEntityTooLarge, Message: fmt.Sprintf("field %s is too long", part.FormName())}
+		}
+		*field = string(value)
+	}
+
+	if up == nil && req.File == "" && req.URL == "" {
+		return nil, &apiError{Status: http.StatusBadRequest, Message: "file part required"}
+	}
+	return up, nil
+}
+
+// saveUpload checks the content of a file part against kind and streams it
+// to a temp file of at most limit bytes
+func (s *Server) saveUpload(part *multipart.Part, kind fetch.Kind, limit int64) (*upload, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(part, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, bodyError(err)
+	}
+	head = head[:n]
+	// The senders sniff the mimetype again, so only the kind is checked here
+	if _, err := fetch.CheckKind(kind, head, part.Header.Get("Content-Type")); err != nil {
+		var fe *fetch.Error
+		if errors.As(err, &fe) {
+			// The fetch error talks about a url
+			return nil, &apiError{Status: http.StatusUnsupportedMediaType, Message: fmt.Sprintf("uploaded file is %s, not %s", mimetypeOf(head, part), kind)}
+		}
+		return nil, err
+	}
+
+	tmp, err := os.CreateTemp(s.TempDir, "api_upload_*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to store upload: %w", err)
+	}
+	up := &upload{path: tmp.Name(), fileName: filepath.Base(filepath.Clean("/" + part.FileName()))}
+	up.size, err = io.Copy(tmp, io.LimitReader(io.MultiReader(bytes.NewReader(head), part), limit+1))
+	if cerr := tmp.Close(); err == nil && cerr != nil {
+		err = fmt.Errorf("failed to store upload: %w", cerr)
+	}
+	if err != nil {
+		return up, bodyError(err)
+	}
+	if up.size > limit {
+		return up, &apiError{Status: http.StatusRequestEntityTooLarge, Message: fmt.Sprintf("file is larger than the limit of %d bytes", limit)}
+	}
+	return up, nil
+}
+
+// mimetypeOf names the content of a rejected upload
+func mimetypeOf(head []byte, part *multipart.Part) string {
+	if sniffed := fetch.Sniff(head); sniffed != "application/octet-stream" {
+		return sniffed
+	}
+	if declared := part.Header.Get("Content-Type"); declared != "" {
+		return declared
+	}
+	return "application/octet-stream"
+}
+
+// bodyError reports an error reading the request body
+func bodyError(err error) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return &apiError{Status: http.StatusRequestEntityTooLarge, Message: fmt.Sprintf("request is larger than the limit of %d bytes", tooLarge.Limit)}
+	}
+	var ae *apiError
+	if errors.As(err, &ae) {
+		return ae
+	}
+	return &apiError{Status: http.StatusBadRequest, Message: fmt.Sprintf("invalid multipart body: %v", err)}
+}
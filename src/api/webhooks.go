@@ -0,0 +1,154 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"whatsappBotGo/src/webhook"
+)
+
+const (
+	defaultDeadLimit = 50
+	maxDeadLimit     = 200
+)
+
+// SetWebhooks queues published events for the webhooks and enables the
+// dead-letter endpoints
+func (s *Server) SetWebhooks(outbox *webhook.Outbox) {
+	s.webhooks = outbox
+}
+
+// checkWebhooks validates the method, user id and outbox of a dead-letter
+// request
+func (s *Server) checkWebhooks(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	allowed := false
+	for _, m := range methods {
+		allowed = allowed || r.Method == m
+	}
+	if !allowed {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return false
+	}
+	if !s.checkQueryUserID(w, r) {
+		return false
+	}
+	if s.webhooks == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "webhooks not configured"})
+		return false
+	}
+	return true
+}
+
+// deadWebhooksHandler lists deliveries that ran out of attempts, newest
+// first. Query parameters: before (cursor), limit.
+func (s *Server) deadWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.checkWebhooks(w, r, http.MethodGet) {
+		return
+	}
+
+	params := r.URL.Query()
+	limit := defaultDeadLimit
+	if v := params.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid limit"})
+			return
+		}
+		limit = min(n, maxDeadLimit)
+	}
+	var before int64
+	if v := params.Get("before"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 1 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid before"})
+			return
+		}
+		before = n
+	}
+
+	page, err := s.webhooks.Dead(before, limit)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("query failed: %v", err)})
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+// deadWebhookHandler returns (GET) or discards (DELETE) one dead delivery
+func (s *Server) deadWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.checkWebhooks(w, r, http.MethodGet, http.MethodDelete) {
+		return
+	}
+	id, ok := deliveryID(w, r)
+	if !ok {
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		if err := s.webhooks.Discard(id); err != nil {
+			writeWebhookError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "discarded"})
+		return
+	}
+
+	delivery, err := s.webhooks.DeadDelivery(id)
+	if err != nil {
+		writeWebhookError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, delivery)
+}
+
+// replayDeadWebhookHandler queues one dead delivery again
+func (s *Server) replayDeadWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.checkWebhooks(w, r, http.MethodPost) {
+		return
+	}
+	id, ok := deliveryID(w, r)
+	if !ok {
+		return
+	}
+	if err := s.webhooks.Replay(id); err != nil {
+		writeWebhookError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "queued"})
+}
+
+// replayDeadWebhooksHandler queues every dead delivery again
+func (s *Server) replayDeadWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.checkWebhooks(w, r, http.MethodPost) {
+		return
+	}
+	n, err := s.webhooks.ReplayAll()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("replay failed: %v", err)})
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]int{"queued": n})
+}
+
+func deliveryID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid delivery id"})
+		return 0, false
+	}
+	return id, true
+}
+
+func writeWebhookError(w http.ResponseWriter, err error) {
+	if errors.Is(err, webhook.ErrNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "delivery not found"})
+		return
+	}
+	if errors.Is(err, webhook.ErrURLRemoved) {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+}
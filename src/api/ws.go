@@ -1,82 +1,480 @@
 package api
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/gorilla/websocket"
+
+	"whatsappBotGo/src/apikeys"
+	"whatsappBotGo/src/functions"
+	"whatsappBotGo/src/journal"
 )
 
-// Hub maintains active connections and broadcasts to them
+// maxFrameSize limits frames sent by clients
+const maxFrameSize = 1 << 20
+
+// HubConfig controls how events are delivered to WebSocket clients
+type HubConfig struct {
+	SendBuffer   int           // Frames queued per client before it counts as slow
+	WriteTimeout time.Duration // Deadline for writing one frame
+	PingInterval time.Duration // 0 disables heartbeats
+	DropSlow     bool          // Drop frames for a slow client instead of disconnecting it
+	MaxActions   int           // Requests a client can have running at once
+}
+
+// HubConfigFromEnv reads the hub configuration from WS_* environment variables
+func HubConfigFromEnv() HubConfig {
+	return HubConfig{
+		SendBuffer:   max(functions.GetEnvInt("WS_SEND_BUFFER", 256), 1),
+		WriteTimeout: time.Duration(functions.GetEnvInt("WS_WRITE_TIMEOUT_SECONDS", 10)) * time.Second,
+		PingInterval: time.Duration(functions.GetEnvInt("WS_PING_INTERVAL_SECONDS", 30)) * time.Second,
+		DropSlow:     functions.GetEnv("WS_SLOW_CLIENT", "disconnect") == "drop",
+		MaxActions:   max(functions.GetEnvInt("WS_MAX_ACTIONS", 8), 1),
+	}
+}
+
+// client is a WebSocket connection, its send queue and the events it
+// subscribed to
+type client struct {
+	conn      *websocket.Conn
+	send      chan []byte
+	done      chan struct{}
+	closeOnce sync.Once
+	filter    *matcher      // Guarded by Hub.mu
+	replay    *replay       // Events to send before live ones, if resuming
+	key       *apikeys.Key  // Nil if authentication is disabled
+	legacy    bool          // Also send the deprecated WSMessage frames
+	actions   chan struct{} // Slots for requests in flight
+
+	// held keeps the frames queued while the journal is replayed, up to
+	// the size of the send queue
+	heldMu    sync.Mutex
+	replaying bool
+	held      [][]byte
+}
+
+// hold keeps data until the replay is done. It reports whether data was
+// held and whether the client is replaying; a replaying client whose held
+// frames already fill limit is slow.
+func (cl *client) hold(data []byte, limit int) (held, replaying bool) {
+	cl.heldMu.Lock()
+	defer cl.heldMu.Unlock()
+	if !cl.replaying {
+		return false, false
+	}
+	if len(cl.held) >= limit {
+		return false, true
+	}
+	cl.held = append(cl.held, data)
+	return true, true
+}
+
+// takeHeld returns the held frames, or ends the replay if there are none
+// left so the next frames go through the send queue
+func (cl *client) takeHeld() [][]byte {
+	cl.heldMu.Lock()
+	defer cl.heldMu.Unlock()
+	held := cl.held
+	cl.held = nil
+	if len(held) == 0 {
+		cl.replaying = false
+	}
+	return held
+}
+
+// close stops the write goroutine and closes the connection, which ends the
+// read loop
+func (cl *client) close() {
+	cl.closeOnce.Do(func() {
+		close(cl.done)
+		cl.conn.Close()
+	})
+}
+
+// HubStats counts delivered and dropped events
+type HubStats struct {
+	Clients   int    `json:"clients"`
+	Broadcast uint64 `json:"events_broadcast"`
+	Dropped   uint64 `json:"events_dropped"`  // Frames not delivered to a slow client
+	Evicted   uint64 `json:"clients_evicted"` // Clients disconnected for being slow
+}
+
+// Hub maintains active connections and broadcasts to them. Every connection
+// has its own bounded queue and write goroutine, so a slow client never
+// blocks Broadcast or the other clients.
 type Hub struct {
-	conns      map[*websocket.Conn]bool
-	broadcast  chan WSMessage
-	register   chan *websocket.Conn
-	unregister chan *websocket.Conn
-	mu         sync.Mutex
+	cfg     HubConfig
+	clients map[*client]bool
+	actions map[string]action
+	journal *journal.Store
+	mu      sync.RWMutex
+	pubMu   sync.Mutex // Keeps sequence numbers in order across clients
+
+	broadcast atomic.Uint64
+	dropped   atomic.Uint64
+	evicted   atomic.Uint64
 }
 
-func NewHub() *Hub {
+// ActionHandler runs an action requested by a client. data is the whole
+// frame; the result is sent back in the ack.
+type ActionHandler func(data []byte) (result any, err error)
+
+// action is a registered handler and the scope a client's key needs to run it
+type action struct {
+	scope apikeys.Scope
+	fn    ActionHandler
+}
+
+func NewHub(cfg HubConfig) *Hub {
 	return &Hub{
-		conns:      make(map[*websocket.Conn]bool),
-		broadcast:  make(chan WSMessage),
-		register:   make(chan *websocket.Conn),
-		unregister: make(chan *websocket.Conn),
+		cfg:     cfg,
+		clients: make(map[*client]bool),
+		actions: make(map[string]action),
 	}
 }
 
+// HandleAction registers fn for frames with the given action, allowed to
+// clients whose key grants scope. Register actions before the hub starts
+// serving connections.
+func (h *Hub) HandleAction(name string, scope apikeys.Scope, fn ActionHandler) {
+	h.actions[name] = action{scope: scope, fn: fn}
+}
+
 var upgrader = websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
 
-func (h *Hub) Run() {
-	for {
-		select {
-		case c := <-h.register:
-			h.mu.Lock()
-			h.conns[c] = true
-			h.mu.Unlock()
-		case c := <-h.unregister:
-			h.mu.Lock()
-			if _, ok := h.conns[c]; ok {
-				delete(h.conns, c)
-				c.Close()
-			}
-			h.mu.Unlock()
-		case msg := <-h.broadcast:
-			// Broadcast to all connections
-			h.mu.Lock()
-			for c := range h.conns {
-				if err := c.WriteJSON(msg); err != nil {
-					log.Printf("ws write error: %v", err)
-					delConn := c
-					delete(h.conns, delConn)
-					delConn.Close()
-				}
-			}
-			h.mu.Unlock()
-		}
-	}
+// ClientFrame is the header of a frame sent by a WebSocket client. Action
+// specific fields sit next to it in the same JSON object.
+type ClientFrame struct {
+	Action    string `json:"action"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// SubscribeFrame replaces the filter of the connection
+type SubscribeFrame struct {
+	ClientFrame
+	Filter
+}
+
+// Reply answers a client frame; RequestID is copied from the frame
+type Reply struct {
+	Version   int    `json:"v"`
+	Type      string `json:"type"` // "ack" or "error"
+	RequestID string `json:"request_id,omitempty"`
+	Result    any    `json:"result,omitempty"`
+	Error     string `json:"error,omitempty"`
 }
 
 func (h *Hub) ServeWS(w http.ResponseWriter, r *http.Request) {
+	// The filter and resume cursor can be given up front so the replay
+	// is filtered too
+	filter, err := filterFromQuery(r.URL.Query()).compile()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	since, resume, err := h.parseSince(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	legacy, err := parseLegacy(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	c, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		http.Error(w, "websocket upgrade failed", http.StatusBadRequest)
 		return
 	}
 
-	h.register <- c
+	cl := &client{
+		conn:    c,
+		send:    make(chan []byte, h.cfg.SendBuffer),
+		done:    make(chan struct{}),
+		filter:  filter,
+		key:     keyFromContext(r.Context()),
+		legacy:  legacy,
+		actions: make(chan struct{}, h.cfg.MaxActions),
+	}
+	// Register between two broadcasts: everything up to until is replayed
+	// from the journal, everything after it arrives live
+	h.pubMu.Lock()
+	if resume {
+		cl.replay = &replay{since: since, until: h.journal.Last()}
+		cl.replaying = true
+	}
+	h.mu.Lock()
+	h.clients[cl] = true
+	h.mu.Unlock()
+	h.pubMu.Unlock()
+	defer h.remove(cl)
+
+	go h.writePump(cl)
 
-	// keep reading to avoid connection closure on client senders
+	// A client that doesn't answer two pings in a row is gone
+	c.SetReadLimit(maxFrameSize)
+	extendDeadline := func() {
+		if h.cfg.PingInterval > 0 {
+			c.SetReadDeadline(time.Now().Add(2 * h.cfg.PingInterval))
+		}
+	}
+	extendDeadline()
+	c.SetPongHandler(func(string) error { extendDeadline(); return nil })
+
+	// read client frames until the connection closes
 	for {
-		_, _, err := c.ReadMessage()
+		_, data, err := c.ReadMessage()
 		if err != nil {
-			h.unregister <- c
 			return
 		}
+		extendDeadline()
+		h.handleFrame(cl, data)
+	}
+}
+
+// writePump is the only writer of a connection: it sends queued frames and
+// heartbeats until the client is closed
+func (h *Hub) writePump(cl *client) {
+	defer cl.close()
+
+	if cl.replay != nil {
+		if err := h.replay(cl); err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Printf("Failed to replay events: %v", err)
+			}
+			return
+		}
+		// Frames keep being held while the earlier ones are written
+		for held := cl.takeHeld(); len(held) > 0; held = cl.takeHeld() {
+			for _, data := range held {
+				if err := h.write(cl, data); err != nil {
+					return
+				}
+			}
+		}
+	}
+
+	var ping <-chan time.Time
+	if h.cfg.PingInterval > 0 {
+		ticker := time.NewTicker(h.cfg.PingInterval)
+		defer ticker.Stop()
+		ping = ticker.C
+	}
+
+	for {
+		select {
+		case data := <-cl.send:
+			if err := h.write(cl, data); err != nil {
+				return
+			}
+		case <-ping:
+			if err := cl.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(h.cfg.WriteTimeout)); err != nil {
+				return
+			}
+		case <-cl.done:
+			return
+		}
+	}
+}
+
+// write sends one frame within the write timeout
+func (h *Hub) write(cl *client, data []byte) error {
+	cl.conn.SetWriteDeadline(time.Now().Add(h.cfg.WriteTimeout))
+	err := cl.conn.WriteMessage(websocket.TextMessage, data)
+	if err != nil && !errors.Is(err, net.ErrClosed) {
+		log.Printf("ws write error: %v", err)
+	}
+	return err
+}
+
+// remove unregisters and closes a client
+func (h *Hub) remove(cl *client) {
+	h.mu.Lock()
+	delete(h.clients, cl)
+	h.mu.Unlock()
+	cl.close()
+}
+
+// enqueue queues a frame without blocking. When the queue is full the frame
+// is dropped and, unless slow clients are kept, the client is disconnected.
+// Frames for a client that is still replaying are held instead, within the
+// same limit.
+func (h *Hub) enqueue(cl *client, data []byte) {
+	held, replaying := cl.hold(data, h.cfg.SendBuffer)
+	if held {
+		return
+	}
+	if !replaying {
+		select {
+		case cl.send <- data:
+			return
+		case <-cl.done:
+			return
+		default:
+		}
+	}
+
+	h.dropped.Add(1)
+	if !h.cfg.DropSlow {
+		h.evicted.Add(1)
+		log.Printf("Disconnecting slow websocket client %s: %d frames queued", cl.conn.RemoteAddr(), h.cfg.SendBuffer)
+		cl.close()
+	}
+}
+
+// handleFrame runs the action of a client frame and replies. Actions run in
+// their own goroutine so a slow send doesn't hold up the client's other
+// requests; request_id tells the replies apart. A client with MaxActions
+// requests running gets a "busy" error until one of them finishes.
+func (h *Hub) handleFrame(cl *client, data []byte) {
+	var frame ClientFrame
+	if err := json.Unmarshal(data, &frame); err != nil {
+		h.reply(cl, Reply{Type: "error", Error: "invalid json frame"})
+		return
+	}
+
+	if frame.Action == "subscribe" {
+		h.reply(cl, h.subscribe(cl, frame, data))
+		return
+	}
+	a, ok := h.actions[frame.Action]
+	if !ok {
+		h.reply(cl, Reply{Type: "error", RequestID: frame.RequestID, Error: "unknown action " + frame.Action})
+		return
+	}
+	if cl.key != nil && !cl.key.Allows(a.scope) {
+		h.reply(cl, Reply{Type: "error", RequestID: frame.RequestID, Error: fmt.Sprintf("api key lacks the %s scope", a.scope)})
+		return
+	}
+	select {
+	case cl.actions <- struct{}{}:
+	default:
+		h.reply(cl, Reply{Type: "error", RequestID: frame.RequestID, Error: "busy"})
+		return
+	}
+	go func() {
+		defer func() { <-cl.actions }()
+		reply := Reply{Type: "ack", RequestID: frame.RequestID}
+		result, err := a.fn(data)
+		if err != nil {
+			reply.Type, reply.Error = "error", err.Error()
+		} else {
+			reply.Result = result
+		}
+		h.reply(cl, reply)
+	}()
+}
+
+// subscribe replaces the filter of the client
+func (h *Hub) subscribe(cl *client, frame ClientFrame, data []byte) Reply {
+	reply := Reply{Type: "ack", RequestID: frame.RequestID}
+	var sub SubscribeFrame
+	if err := json.Unmarshal(data, &sub); err != nil {
+		reply.Type, reply.Error = "error", "invalid subscribe frame"
+		return reply
+	}
+	filter, err := sub.Filter.compile()
+	if err != nil {
+		reply.Type, reply.Error = "error", err.Error()
+		return reply
+	}
+	h.mu.Lock()
+	cl.filter = filter
+	h.mu.Unlock()
+	reply.Result = sub.Filter
+	return reply
+}
+
+// reply queues a reply to a client frame
+func (h *Hub) reply(cl *client, reply Reply) {
+	reply.Version = EventVersion
+	data, err := json.Marshal(reply)
+	if err != nil {
+		log.Printf("Failed to encode websocket reply: %v", err)
+		return
+	}
+	h.enqueue(cl, data)
+}
+
+// Broadcast records an event in the journal, if there is one, and queues it
+// for every client whose filter matches. It never blocks on a client. The
+// encoded event is returned, nil if it could not be encoded.
+func (h *Hub) Broadcast(msg Event) []byte {
+	h.pubMu.Lock()
+	defer h.pubMu.Unlock()
+
+	data, err := h.record(&msg)
+	if err != nil {
+		log.Printf("Failed to record %s event: %v", msg.Type, err)
+	}
+	if data == nil {
+		return nil
+	}
+	h.broadcast.Add(1)
+
+	// Legacy frames are neither journaled nor replayed, and only encoded
+	// if a client asked for them
+	var legacy []byte
+	legacyDone := false
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for cl := range h.clients {
+		if !cl.filter.match(msg.Type, msg.scope) {
+			continue
+		}
+		h.enqueue(cl, data)
+		if cl.legacy {
+			if !legacyDone {
+				legacy, legacyDone = legacyData(msg), true
+			}
+			if legacy != nil {
+				h.enqueue(cl, legacy)
+			}
+		}
 	}
+	return data
 }
 
-func (h *Hub) Broadcast(msg WSMessage) {
-	h.broadcast <- msg
+// Stats returns the number of clients and event counters
+func (h *Hub) Stats() HubStats {
+	h.mu.RLock()
+	clients := len(h.clients)
+	h.mu.RUnlock()
+	return HubStats{
+		Clients:   clients,
+		Broadcast: h.broadcast.Load(),
+		Dropped:   h.dropped.Load(),
+		Evicted:   h.evicted.Load(),
+	}
+}
+
+// disconnectKey closes the connections opened with a revoked key
+func (h *Hub) disconnectKey(id string) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for cl := range h.clients {
+		if cl.key != nil && cl.key.ID == id {
+			cl.close()
+		}
+	}
+}
+
+// Close disconnects all clients
+func (h *Hub) Close() {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for cl := range h.clients {
+		cl.close()
+	}
 }
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"go.mau.fi/whatsmeow/types"
+
+	"whatsappBotGo/src/apikeys"
+	"whatsappBotGo/src/fetch"
+)
+
+// registerActions makes the send endpoints available over the WebSocket
+func (s *Server) registerActions() {
+	s.Hub.HandleAction("send_text", apikeys.ScopeSend, s.wsSendText)
+	for _, kind := range mediaKinds {
+		s.Hub.HandleAction("send_"+string(kind), apikeys.ScopeSend, s.wsSendMedia(kind))
+	}
+	s.Hub.HandleAction("react", apikeys.ScopeSend, s.wsReact)
+	s.Hub.HandleAction("mark_read", apikeys.ScopeSend, s.wsMarkRead)
+	s.Hub.HandleAction("typing", apikeys.ScopeSend, s.wsTyping)
+}
+
+// checkUserID validates the user_id of a request against the instance
+func (s *Server) checkUserID(userID string) error {
+	if s.InstanceUserID == "" {
+		return nil
+	}
+	if userID == "" {
+		return &apiError{Status: http.StatusBadRequest, Message: "user_id required"}
+	}
+	if userID != s.InstanceUserID {
+		return &apiError{Status: http.StatusForbidden, Message: "user_id mismatch"}
+	}
+	return nil
+}
+
+// decodeAction decodes the fields of a frame into req
+func decodeAction(data []byte, req any) error {
+	if err := json.Unmarshal(data, req); err != nil {
+		return errors.New("invalid json payload")
+	}
+	return nil
+}
+
+func (s *Server) wsSendText(data []byte) (any, error) {
+	var req SendTextRequest
+	if err := decodeAction(data, &req); err != nil {
+		return nil, err
+	}
+	if err := s.checkUserID(req.UserID); err != nil {
+		return nil, err
+	}
+	jid, err := types.ParseJID(req.JID)
+	if err != nil {
+		return nil, errors.New("invalid jid")
+	}
+	if s.senders == nil || s.senders.Text == nil {
+		return nil, errors.New("text sender not configured")
+	}
+	result, err := s.senders.Text.SendText(jid, req.Text)
+	if err != nil {
+		return nil, fmt.Errorf("send failed: %v", err)
+	}
+	return result, nil
+}
+
+// wsSendMedia returns the action sending media of kind
+func (s *Server) wsSendMedia(kind fetch.Kind) ActionHandler {
+	return func(data []byte) (any, error) {
+		var req SendMediaRequest
+		if err := decodeAction(data, &req); err != nil {
+			return nil, err
+		}
+		return s.sendMedia(kind, req, nil)
+	}
+}
+
+func (s *Server) wsReact(data []byte) (any, error) {
+	var req ReactRequest
+	if err := decodeAction(data, &req); err != nil {
+		return nil, err
+	}
+	if err := s.checkUserID(req.UserID); err != nil {
+		return nil, err
+	}
+	chat, err := types.ParseJID(req.Chat)
+	if err != nil {
+		return nil, errors.New("invalid chat")
+	}
+	sender, err := types.ParseJID(req.Sender)
+	if err != nil {
+		return nil, errors.New("invalid sender")
+	}
+	if req.MessageID == "" {
+		return nil, errors.New("message_id required")
+	}
+	if s.senders == nil || s.senders.Reaction == nil {
+		return nil, errors.New("reaction sender not configured")
+	}
+	result, err := s.senders.Reaction.SendReaction(chat, sender, req.MessageID, req.Emoji)
+	if err != nil {
+		return nil, fmt.Errorf("send failed: %v", err)
+	}
+	return result, nil
+}
+
+func (s *Server) wsMarkRead(data []byte) (any, error) {
+	var req MarkReadRequest
+	if err := decodeAction(data, &req); err != nil {
+		return nil, err
+	}
+	if err := s.checkUserID(req.UserID); err != nil {
+		return nil, err
+	}
+	chat, err := types.ParseJID(req.Chat)
+	if err != nil {
+		return nil, errors.New("invalid chat")
+	}
+	var sender types.JID
+	if req.Sender != "" {
+		if sender, err = types.ParseJID(req.Sender); err != nil {
+			return nil, errors.New("invalid sender")
+		}
+	}
+	if len(req.MessageIDs) == 0 {
+		return nil, errors.New("message_ids required")
+	}
+	if s.senders == nil || s.senders.Chat == nil {
+		return nil, errors.New("chat state sender not configured")
+	}
+	if err := s.senders.Chat.MarkRead(chat, sender, req.MessageIDs...); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (s *Server) wsTyping(data []byte) (any, error) {
+	var req TypingRequest
+	if err := decodeAction(data, &req); err != nil {
+		return nil, err
+	}
+	if err := s.checkUserID(req.UserID); err != nil {
+		return nil, err
+	}
+	jid, err := types.ParseJID(req.JID)
+	if err != nil {
+		return nil, errors.New("invalid jid")
+	}
+	var typing, recording bool
+	switch req.State {
+	case "typing":
+		typing = true
+	case "recording":
+		recording = true
+	case "paused":
+	default:
+		return nil, errors.New("state must be typing, recording or paused")
+	}
+	if s.senders == nil || s.senders.Chat == nil {
+		return nil, errors.New("chat state sender not configured")
+	}
+	if err := s.senders.Chat.SetTyping(jid, typing, recording); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
@@ -0,0 +1,88 @@
+package apikeys
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"whatsappBotGo/src/internal/store"
+)
+
+const usage = `usage:
+  apikey create -name <name> -scopes <send,read,events,admin>
+  apikey list
+  apikey revoke <id>`
+
+// RunCLI manages keys from the command line, e.g. to create the first admin
+// key before the API can be used. args follow the "apikey" subcommand.
+func RunCLI(dbPath string, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+
+	db, err := store.Open(dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	keys, err := NewStore(db)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "create":
+		flags := flag.NewFlagSet("apikey create", flag.ContinueOnError)
+		flags.SetOutput(out)
+		name := flags.String("name", "", "name of the key, e.g. the client using it")
+		scopeList := flags.String("scopes", "", "comma-separated scopes: send, read, events, admin")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		scopes, err := ParseScopes(*scopeList)
+		if err != nil {
+			return err
+		}
+		key, token, err := keys.Create(*name, scopes)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Created key %s (%s) with scopes %s\n", key.ID, key.Name, joinScopes(key.Scopes))
+		fmt.Fprintf(out, "Token, shown only once:\n%s\n", token)
+		return nil
+
+	case "list":
+		list, err := keys.List()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tPREFIX\tSCOPES\tCREATED\tLAST USED\tREVOKED")
+		for _, key := range list {
+			fmt.Fprintf(w, "%s\t%s\t%s…\t%s\t%s\t%s\t%s\n", key.ID, key.Name, key.Prefix, joinScopes(key.Scopes),
+				key.CreatedAt.Format("2006-01-02 15:04"), formatTime(key.LastUsedAt), formatTime(key.RevokedAt))
+		}
+		return w.Flush()
+
+	case "revoke":
+		if len(args) != 2 {
+			return errors.New(usage)
+		}
+		if err := keys.Revoke(args[1]); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Revoked key %s\n", args[1])
+		return nil
+	}
+	return fmt.Errorf("unknown command %q\n%s", args[0], usage)
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format("2006-01-02 15:04")
+}
@@ -0,0 +1,242 @@
+package apikeys
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidKey is returned for unknown or revoked tokens
+	ErrInvalidKey = errors.New("invalid api key")
+	// ErrNotFound is returned for unknown key IDs
+	ErrNotFound = errors.New("api key not found")
+)
+
+// tokenPrefix marks tokens issued by this bot so they are easy to spot in
+// configs and secret scanners
+const tokenPrefix = "wab_"
+
+// usedInterval is how often the last use of a key is written at most
+const usedInterval = time.Minute
+
+// Scope is a permission granted to a key
+type Scope string
+
+const (
+	ScopeSend   Scope = "send"   // Send messages, reactions, read receipts and typing state
+	ScopeRead   Scope = "read"   // Read media, history, message status and health
+	ScopeEvents Scope = "events" // Connect to the WebSocket
+	ScopeAdmin  Scope = "admin"  // Everything, including keys, webhooks and stopping the bot
+)
+
+// Scopes lists every scope
+var Scopes = []Scope{ScopeSend, ScopeRead, ScopeEvents, ScopeAdmin}
+
+// ParseScopes parses a comma-separated list of scopes
+func ParseScopes(list string) ([]Scope, error) {
+	var scopes []Scope
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if !slices.Contains(Scopes, Scope(item)) {
+			return nil, fmt.Errorf("unknown scope %q", item)
+		}
+		if !slices.Contains(scopes, Scope(item)) {
+			scopes = append(scopes, Scope(item))
+		}
+	}
+	if len(scopes) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+	return scopes, nil
+}
+
+// Key is an API key without its secret
+type Key struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"` // Start of the token, to tell keys apart
+	Scopes     []Scope    `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Allows reports whether the key grants scope; admin grants every scope
+func (k *Key) Allows(scope Scope) bool {
+	return slices.Contains(k.Scopes, ScopeAdmin) || slices.Contains(k.Scopes, scope)
+}
+
+// Store keeps API keys. Only the SHA-256 of a token is stored; the token
+// itself is shown once, when the key is created.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates the key table if needed
+func NewStore(db *sql.DB) (*Store, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS bot_api_keys (
+		id           TEXT PRIMARY KEY,
+		name         TEXT NOT NULL,
+		prefix       TEXT NOT NULL,
+		hash         TEXT NOT NULL UNIQUE,
+		scopes       TEXT NOT NULL,
+		created_at   INTEGER NOT NULL,
+		last_used_at INTEGER,
+		revoked_at   INTEGER
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create api key table: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Create issues a new key and returns it with its token
+func (s *Store) Create(name string, scopes []Scope) (*Key, string, error) {
+	if name = strings.TrimSpace(name); name == "" {
+		return nil, "", errors.New("name required")
+	}
+	if len(scopes) == 0 {
+		return nil, "", errors.New("at least one scope is required")
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+	id := make([]byte, 6)
+	if _, err := rand.Read(id); err != nil {
+		return nil, "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+
+	token := tokenPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	key := &Key{
+		ID:        hex.EncodeToString(id),
+		Name:      name,
+		Prefix:    token[:len(tokenPrefix)+6],
+		Scopes:    scopes,
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+	_, err := s.db.Exec(`INSERT INTO bot_api_keys (id, name, prefix, hash, scopes, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		key.ID, key.Name, key.Prefix, hash(token), joinScopes(scopes), key.CreatedAt.Unix())
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to store api key: %w", err)
+	}
+	return key, token, nil
+}
+
+// Authenticate returns the active key of token and records its use
+func (s *Store) Authenticate(token string) (*Key, error) {
+	if !strings.HasPrefix(token, tokenPrefix) {
+		return nil, ErrInvalidKey
+	}
+	key, err := scanKey(s.db.QueryRow(`SELECT id, name, prefix, scopes, created_at, last_used_at, revoked_at
+		FROM bot_api_keys WHERE hash = ? AND revoked_at IS NULL`, hash(token)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= usedInterval {
+		if _, err := s.db.Exec(`UPDATE bot_api_keys SET last_used_at = ? WHERE id = ?`, now.Unix(), key.ID); err != nil {
+			return nil, fmt.Errorf("failed to update api key: %w", err)
+		}
+	}
+	return key, nil
+}
+
+// List returns all keys, including revoked ones, oldest first
+func (s *Store) List() ([]Key, error) {
+	rows, err := s.db.Query(`SELECT id, name, prefix, scopes, created_at, last_used_at, revoked_at
+		FROM bot_api_keys ORDER BY created_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []Key{}
+	for rows.Next() {
+		key, err := scanKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+	return keys, rows.Err()
+}
+
+// Active returns the number of keys that are not revoked
+func (s *Store) Active() (int, error) {
+	var n int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM bot_api_keys WHERE revoked_at IS NULL`).Scan(&n); err != nil {
+		return 0, fmt.Errorf("failed to count api keys: %w", err)
+	}
+	return n, nil
+}
+
+// Revoke disables a key; it is kept in the list for auditing
+func (s *Store) Revoke(id string) error {
+	res, err := s.db.Exec(`UPDATE bot_api_keys SET revoked_at = COALESCE(revoked_at, ?) WHERE id = ?`, time.Now().Unix(), id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func scanKey(row interface{ Scan(...any) error }) (*Key, error) {
+	var key Key
+	var scopes string
+	var created int64
+	var used, revoked sql.NullInt64
+	err := row.Scan(&key.ID, &key.Name, &key.Prefix, &scopes, &created, &used, &revoked)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read api key: %w", err)
+	}
+	for _, scope := range strings.Split(scopes, ",") {
+		key.Scopes = append(key.Scopes, Scope(scope))
+	}
+	key.CreatedAt = time.Unix(created, 0).UTC()
+	if used.Valid {
+		t := time.Unix(used.Int64, 0).UTC()
+		key.LastUsedAt = &t
+	}
+	if revoked.Valid {
+		t := time.Unix(revoked.Int64, 0).UTC()
+		key.RevokedAt = &t
+	}
+	return &key, nil
+}
+
+func joinScopes(scopes []Scope) string {
+	items := make([]string, len(scopes))
+	for i, scope := range scopes {
+		items[i] = string(scope)
+	}
+	return strings.Join(items, ",")
+}
+
+// hash returns the stored form of a token. Tokens are random, so a plain
+// SHA-256 is enough; a slow password hash would only slow down requests.
+func hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,186 @@
+package args
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// ErrUnterminatedQuote is returned when a quoted argument is never closed
+var ErrUnterminatedQuote = errors.New("unterminated quoted argument")
+
+// Args holds a parsed command invocation
+type Args struct {
+	Command    string            // Lowercased command name without prefix, e.g. "echo"
+	Positional []string          // Positional arguments with their original case
+	Flags      map[string]string // Options given as --name=value, --name or -n
+	Raw        string            // Everything after the command name, verbatim
+
+	offsets []int // Start offset of each positional argument within Raw
+}
+
+// Parse splits a command message, with its prefix already removed, into its
+// name, arguments and options.
+//
+// Only the command name is lowercased. Arguments may be wrapped in single or
+// double quotes to keep spaces, and double-quoted arguments accept \" and \\
+// escapes. Quotes inside a word, such as apostrophes, are kept as is. Options take the form --name=value, --name (set to "true") or -n,
+// where -abc sets a, b and c. A bare "--" stops option parsing so that the
+// remaining tokens are kept as positional arguments.
+func Parse(message string) (*Args, error) {
+	message = strings.TrimSpace(message)
+	name, raw := message, ""
+	if i := strings.IndexFunc(message, unicode.IsSpace); i >= 0 {
+		name, raw = message[:i], strings.TrimLeftFunc(message[i:], unicode.IsSpace)
+	}
+
+	a := &Args{
+		Command:    strings.ToLower(name),
+		Positional: []string{},
+		Flags:      make(map[string]string),
+		Raw:        raw,
+	}
+
+	tokens, offsets, quoted, err := tokenize(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	optionsDone := false
+	for i, tok := range tokens {
+		if !optionsDone && !quoted[i] {
+			if tok == "--" {
+				optionsDone = true
+				continue
+			}
+			if a.parseOption(tok) {
+				continue
+			}
+		}
+		a.Positional = append(a.Positional, tok)
+		a.offsets = append(a.offsets, offsets[i])
+	}
+	return a, nil
+}
+
+// parseOption records tok as an option and reports whether it was one
+func (a *Args) parseOption(tok string) bool {
+	if strings.HasPrefix(tok, "--") {
+		name, value, ok := strings.Cut(tok[2:], "=")
+		// "---x" and "--=x" have no usable name and are kept as arguments
+		if name == "" || name[0] == '-' {
+			return false
+		}
+		if !ok {
+			value = "true"
+		}
+		a.Flags[strings.ToLower(name)] = value
+		return true
+	}
+
+	// Single dash options; "-5" is treated as a negative number, not a flag
+	if len(tok) > 1 && tok[0] == '-' && !unicode.IsDigit(rune(tok[1])) {
+		for _, r := range tok[1:] {
+			a.Flags[string(r)] = "true"
+		}
+		return true
+	}
+	return false
+}
+
+// tokenize splits s on whitespace while honouring quotes
+func tokenize(s string) (tokens []string, offsets []int, quoted []bool, err error) {
+	var (
+		cur      strings.Builder
+		inToken  bool
+		wasQuote bool
+		start    int
+		quote    rune
+		escaped  bool
+	)
+
+	flush := func() {
+		if inToken {
+			tokens = append(tokens, cur.String())
+			offsets = append(offsets, start)
+			quoted = append(quoted, wasQuote)
+		}
+		cur.Reset()
+		inToken, wasQuote = false, false
+	}
+
+	for i, r := range s {
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+		case quote != 0:
+			if r == '\\' && quote == '"' {
+				escaped = true
+			} else if r == quote {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case (r == '"' || r == '\'') && !inToken:
+			// Only a quote that starts a token opens one, so apostrophes
+			// as in "don't" stay literal
+			inToken, start = true, i
+			quote, wasQuote = r, true
+		case unicode.IsSpace(r):
+			flush()
+		default:
+			if !inToken {
+				inToken, start = true, i
+			}
+			cur.WriteRune(r)
+		}
+	}
+
+	if quote != 0 || escaped {
+		return nil, nil, nil, ErrUnterminatedQuote
+	}
+	flush()
+	return tokens, offsets, quoted, nil
+}
+
+// Len returns the number of positional arguments
+func (a *Args) Len() int {
+	return len(a.Positional)
+}
+
+// Arg returns the i-th positional argument or "" if it is missing
+func (a *Args) Arg(i int) string {
+	if i < 0 || i >= len(a.Positional) {
+		return ""
+	}
+	return a.Positional[i]
+}
+
+// Flag returns the value of an option and whether it was given
+func (a *Args) Flag(name string) (string, bool) {
+	v, ok := a.Flags[strings.ToLower(name)]
+	return v, ok
+}
+
+// Bool reports whether a boolean option was given and not set to a false value
+func (a *Args) Bool(name string) bool {
+	v, ok := a.Flag(name)
+	if !ok {
+		return false
+	}
+	switch strings.ToLower(v) {
+	case "false", "0", "no", "off":
+		return false
+	}
+	return true
+}
+
+// Rest returns the raw text starting at the i-th positional argument,
+// keeping its original spacing and quotes
+func (a *Args) Rest(i int) string {
+	if i < 0 || i >= len(a.offsets) {
+		return ""
+	}
+	return a.Raw[a.offsets[i]:]
+}
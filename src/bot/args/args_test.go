@@ -0,0 +1,60 @@
+package args
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		message    string
+		positional []string
+		flags      map[string]string
+	}{
+		{"echo I'm here", []string{"I'm", "here"}, map[string]string{}},
+		{"echo don't stop", []string{"don't", "stop"}, map[string]string{}},
+		{"echo it's 5 o'clock", []string{"it's", "5", "o'clock"}, map[string]string{}},
+		{`echo "hello world" 'single quoted'`, []string{"hello world", "single quoted"}, map[string]string{}},
+		{`echo "say \"hi\""`, []string{`say "hi"`}, map[string]string{}},
+		{`echo a"b c"`, []string{`a"b`, `c"`}, map[string]string{}},
+		{"echo --name=Bob -ab --force", []string{}, map[string]string{"name": "Bob", "a": "true", "b": "true", "force": "true"}},
+		{"echo -5 ---x --=x", []string{"-5", "---x", "--=x"}, map[string]string{}},
+		{"echo -- --name", []string{"--name"}, map[string]string{}},
+		{`echo "--name"`, []string{"--name"}, map[string]string{}},
+	}
+	for _, tt := range tests {
+		a, err := Parse(tt.message)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.message, err)
+			continue
+		}
+		if !reflect.DeepEqual(a.Positional, tt.positional) {
+			t.Errorf("Parse(%q).Positional = %q, want %q", tt.message, a.Positional, tt.positional)
+		}
+		if !reflect.DeepEqual(a.Flags, tt.flags) {
+			t.Errorf("Parse(%q).Flags = %v, want %v", tt.message, a.Flags, tt.flags)
+		}
+	}
+}
+
+func TestParseUnterminatedQuote(t *testing.T) {
+	for _, message := range []string{`echo "open`, `echo 'open`, `echo "trailing \`} {
+		if _, err := Parse(message); !errors.Is(err, ErrUnterminatedQuote) {
+			t.Errorf("Parse(%q) error = %v, want ErrUnterminatedQuote", message, err)
+		}
+	}
+}
+
+func TestRest(t *testing.T) {
+	a, err := Parse(`say  don't   "keep  this"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := a.Rest(0), `don't   "keep  this"`; got != want {
+		t.Errorf("Rest(0) = %q, want %q", got, want)
+	}
+	if got, want := a.Rest(1), `"keep  this"`; got != want {
+		t.Errorf("Rest(1) = %q, want %q", got, want)
+	}
+}
@@ -2,12 +2,15 @@ package bot
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
 	"strings"
+	"sync"
 	"syscall"
+	"time"
 
 	"github.com/skip2/go-qrcode"
 	"go.mau.fi/whatsmeow"
@@ -19,20 +22,49 @@ import (
 	_ "modernc.org/sqlite"
 
 	"whatsappBotGo/src/api"
+	"whatsappBotGo/src/apikeys"
+	"whatsappBotGo/src/bot/response"
+	"whatsappBotGo/src/bot/roles"
+	"whatsappBotGo/src/bot/settings"
+	"whatsappBotGo/src/commands/admin"
 	"whatsappBotGo/src/commands/fun"
 	"whatsappBotGo/src/commands/system"
 	"whatsappBotGo/src/functions"
+	"whatsappBotGo/src/history"
+	"whatsappBotGo/src/internal/store"
+	"whatsappBotGo/src/internal/utils"
+	"whatsappBotGo/src/journal"
+	"whatsappBotGo/src/media"
+	"whatsappBotGo/src/receipts"
 	"whatsappBotGo/src/senders"
+	"whatsappBotGo/src/webhook"
+	"whatsappBotGo/src/whats"
 )
 
 // Keep the same structure as previous bot.Bot
 type WhatsAppBot struct {
 	client         *whatsmeow.Client
 	store          *sqlstore.Container
+	db             *sql.DB
 	commandHandler *CommandHandler
+	groupAdmins    *groupAdminCache
+	maintenance    *Maintenance
+	metrics        *MessageMetrics
 	sender         *senders.Senders
 	apiServer      *api.Server
 	instanceUserID string
+	mediaStore     *media.Store
+	history        *history.Store
+	receipts       *receipts.Tracker
+	events         *whats.Events
+	journal        *journal.Store
+	webhooks       *webhook.Outbox
+	apiKeys        *apikeys.Store
+	mediaTypes     map[whats.MessageType]bool
+	mediaSlots     chan struct{} // Semaphore bounding concurrent media downloads
+
+	mwMu        sync.RWMutex
+	middlewares []MessageMiddleware
 }
 
 // Senders returns the aggregated senders object
@@ -76,39 +108,152 @@ func NewBot() (*WhatsAppBot, error) {
 	clientLog := waLog.Stdout("Client", "INFO", true)
 	client := whatsmeow.NewClient(deviceStore, clientLog)
 
+	// Open the bot's own database for data such as the admin roster
+	db, err := store.Open(functions.GetEnv("BOT_DB_PATH", "bot.db"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bot database: %v", err)
+	}
+
+	var owner types.JID
+	if ownerEnv := functions.GetEnv("OWNER_JID", ""); ownerEnv != "" {
+		if owner, err = utils.ParseUserJID(ownerEnv); err != nil {
+			log.Printf("Ignoring invalid OWNER_JID %q: %v", ownerEnv, err)
+		}
+	}
+	roster, err := roles.NewStore(db, owner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load roles: %v", err)
+	}
+	chatSettings, err := settings.NewStore(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chat settings: %v", err)
+	}
+	messages, err := history.NewStore(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open message history: %v", err)
+	}
+	tracker, err := receipts.NewTracker(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open receipt tracker: %v", err)
+	}
+
 	// Create command handler and register commands
+	groupAdmins := newGroupAdminCache(client, 5*time.Minute)
 	commandHandler := NewCommandHandler()
-	registerCommands(commandHandler)
+	commandHandler.SetPermissions(NewPermissions(roster, groupAdmins.IsAdmin))
+	commandHandler.SetChatSettings(chatSettings)
+	maintenance := NewMaintenance(functions.GetEnvBool("MAINTENANCE_MODE", false), commandHandler)
+	registerCommands(commandHandler, roster, chatSettings, maintenance)
 
 	bot := &WhatsAppBot{
 		client:         client,
 		store:          container,
+		db:             db,
 		commandHandler: commandHandler,
+		groupAdmins:    groupAdmins,
+		maintenance:    maintenance,
+		metrics:        &MessageMetrics{},
+		history:        messages,
+		receipts:       tracker,
+	}
+
+	// Default message middlewares; more can be added with bot.Use
+	bot.Use(RecoverMiddleware, bot.metrics.Middleware, LoggingMiddleware)
+	if blacklist := parseJIDList(functions.GetEnv("BLACKLIST_JIDS", "")); len(blacklist) > 0 {
+		bot.Use(BlacklistMiddleware(blacklist...))
+	}
+	bot.Use(HistoryMiddleware(messages))
+	bot.Use(maintenance.Middleware)
+	bot.Use(commandHandler.PermissionMiddleware)
+	if functions.GetEnvBool("RATE_LIMIT_ENABLED", true) {
+		bot.Use(NewRateLimiter(RateLimitConfigFromEnv(), commandHandler).Middleware)
+	}
+
+	// Keep recent websocket events so clients can resume after a disconnect
+	if size := functions.GetEnvInt("EVENT_JOURNAL_SIZE", 10000); size > 0 {
+		if bot.journal, err = journal.NewStore(db, size); err != nil {
+			return nil, fmt.Errorf("failed to open event journal: %v", err)
+		}
+	}
+
+	// Require API keys unless authentication is explicitly turned off
+	if functions.GetEnvBool("API_AUTH", true) {
+		if bot.apiKeys, err = apikeys.NewStore(db); err != nil {
+			return nil, fmt.Errorf("failed to open api keys: %v", err)
+		}
+		if n, err := bot.apiKeys.Active(); err == nil && n == 0 {
+			log.Printf("No API keys yet, all API requests will be rejected. Create one with: apikey create -name admin -scopes admin")
+		}
+	}
+
+	// Push events to webhook endpoints through a persistent outbox
+	if cfg := webhook.ConfigFromEnv(); len(cfg.URLs) > 0 {
+		if bot.webhooks, err = webhook.NewOutbox(db, cfg); err != nil {
+			return nil, fmt.Errorf("failed to open webhook outbox: %v", err)
+		}
+		bot.webhooks.Start()
+	}
+
+	// Download incoming attachments so API clients can fetch them
+	if functions.GetEnvBool("MEDIA_DOWNLOAD", true) {
+		if bot.mediaStore, err = media.NewStore(db, media.ConfigFromEnv()); err != nil {
+			return nil, fmt.Errorf("failed to open media store: %v", err)
+		}
+		bot.mediaTypes = parseMediaTypes(functions.GetEnv("MEDIA_DOWNLOAD_TYPES", "image,video,gif,audio,voice,document,sticker"))
+		bot.mediaSlots = make(chan struct{}, max(1, functions.GetEnvInt("MEDIA_DOWNLOAD_CONCURRENCY", 4)))
+		bot.mediaStore.StartCleanup(time.Hour)
 	}
 
 	// Create senders and set for handler
 	s := senders.NewSendersFromClient(client)
 	commandHandler.SetSenders(s)
 	bot.sender = s
+	s.Hooks.OnSent(bot.recordSent)
+	s.Hooks.OnSent(bot.trackSent)
+	s.Hooks.OnSent(bot.broadcastSent)
 
 	// Bind instance user ID from env or leave empty
 	bot.instanceUserID = functions.GetEnv("INSTANCE_USER_ID", "")
 
-	// Add event handler
-	client.AddEventHandler(bot.eventHandler)
+	// Feed whatsmeow events into the bus
+	bot.events = whats.NewEvents()
+	bot.subscribeEvents()
+	bot.subscribeWebSocket()
+	client.AddEventHandler(bot.events.Handle)
 
 	return bot, nil
 }
 
 // registerCommands registers all available commands
-func registerCommands(handler *CommandHandler) {
-	handler.RegisterCommand(system.NewHelpCommand())
-	handler.RegisterCommand(system.NewPingCommand())
+func registerCommands(handler *CommandHandler, roster *roles.Store, chatSettings *settings.Store, maintenance *Maintenance) {
+	handler.RegisterCommand(system.NewHelpCommand(handler))
+	handler.RegisterResponseCommand(system.NewPingCommand())
 	handler.RegisterCommand(system.NewTimeCommand())
 	handler.RegisterCommand(system.NewEchoCommand())
 	handler.RegisterCommand(system.NewInfoCommand())
+	handler.RegisterCommand(system.NewSuggestionsCommand(chatSettings, handler, handler))
 	handler.RegisterCommand(fun.NewJokeCommand())
 	handler.RegisterCommand(fun.NewQuoteCommand())
+	handler.RegisterCommand(admin.NewStatusCommand())
+	handler.RegisterCommand(admin.NewAdminCommand(roster, handler))
+	handler.RegisterCommand(admin.NewMaintenanceCommand(maintenance))
+}
+
+// parseJIDList parses a comma-separated list of JIDs or phone numbers
+func parseJIDList(list string) []types.JID {
+	var jids []types.JID
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		jid, err := utils.ParseUserJID(item)
+		if err != nil {
+			log.Printf("Ignoring invalid JID %q: %v", item, err)
+			continue
+		}
+		jids = append(jids, jid)
+	}
+	return jids
 }
 
 // Start starts the WhatsApp bot
@@ -122,6 +267,7 @@ func (bot *WhatsAppBot) Start() error {
 		}
 
 		for evt := range qrChan {
+			bot.broadcastQR(evt)
 			if evt.Event == "code" {
 				// Print small QR code in terminal
 				printSmallQR(evt.Code)
@@ -138,82 +284,135 @@ func (bot *WhatsAppBot) Start() error {
 	}
 
 	fmt.Println("Bot is running...")
+	bot.publish(api.EventBot, api.BotPayload{State: "started"})
 
 	// Wait for interrupt signal
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 	<-c
 
-	bot.client.Disconnect()
+	bot.Disconnect()
 	return nil
 }
 
-// eventHandler handles incoming WhatsApp events
-func (bot *WhatsAppBot) eventHandler(evt interface{}) {
-	switch v := evt.(type) {
-	case *events.Message:
-		bot.handleMessage(v)
-	case *events.Receipt:
-		if v.Type == types.ReceiptTypeRead || v.Type == types.ReceiptTypeReadSelf {
-			fmt.Printf("Message %s was read\n", v.MessageIDs[0])
-		}
-	case *events.Presence:
-		if v.Unavailable {
-			fmt.Printf("%s went offline\n", v.From)
-		} else {
-			fmt.Printf("%s is online\n", v.From)
-		}
-	}
+// handleMessage passes an incoming message through the middleware chain
+func (bot *WhatsAppBot) handleMessage(evt *events.Message) {
+	incoming := whats.ParseMessage(evt)
+	msg := &MessageContext{Event: evt, Incoming: incoming, Text: strings.TrimSpace(incoming.Text), bot: bot}
+	bot.messageChain()(msg)
 }
 
-// handleMessage processes incoming messages and responds accordingly
-func (bot *WhatsAppBot) handleMessage(evt *events.Message) {
-	if evt.Info.IsFromMe {
+// processMessage is the end of the middleware chain: it runs commands and
+// auto replies and broadcasts the message
+func (bot *WhatsAppBot) processMessage(msg *MessageContext) {
+	msg.completed = true
+	evt := msg.Event
+	if evt.Info.IsFromMe || evt.Message == nil {
 		return // Ignore own messages
 	}
 
-	msg := evt.Message
-	if msg == nil {
-		return
+	// Text and media captions go through commands and auto replies;
+	// media without a caption and edits are only broadcast
+	if msg.expectsReply() {
+		resp := bot.commandHandler.Respond(msg.Command(), msg.Text, evt, evt.Info.Sender)
+		bot.sendResponse(evt, resp)
 	}
 
-	var messageText string
-	if msg.GetConversation() != "" {
-		messageText = msg.GetConversation()
-	} else if msg.GetExtendedTextMessage() != nil {
-		messageText = msg.GetExtendedTextMessage().GetText()
-	} else {
-		return // Not a text message
+	// Attachments are downloaded in the background so a large file doesn't
+	// hold up other events; the message is broadcast once it is stored
+	if bot.wantsMedia(msg.Incoming) {
+		go func() {
+			// Each download holds its file in memory, so only a few run at once
+			bot.mediaSlots <- struct{}{}
+			err := bot.downloadMedia(msg.Incoming)
+			<-bot.mediaSlots
+			if err != nil {
+				log.Printf("Failed to store media of message %s: %v", evt.Info.ID, err)
+			} else if err := bot.history.SetMediaID(evt.Info.Chat, evt.Info.ID, msg.Incoming.Media.ID); err != nil {
+				log.Printf("Failed to link media of message %s: %v", evt.Info.ID, err)
+			}
+			bot.broadcastIncoming(msg)
+		}()
+		return
 	}
+	bot.broadcastIncoming(msg)
+}
 
-	messageText = strings.TrimSpace(messageText)
+// sendResponse delivers a command or auto-reply response for an incoming message
+func (bot *WhatsAppBot) sendResponse(evt *events.Message, resp *response.Response) {
+	if resp.IsEmpty() {
+		return
+	}
 
-	fmt.Printf("Received message from %s: %s\n", evt.Info.Sender, messageText)
+	chat := evt.Info.Chat
+	if !resp.Chat.IsEmpty() {
+		chat = resp.Chat
+	}
 
 	// Create quoted message reference for replies
-	quotedMsg := &senders.QuotedMessage{
-		MessageID: evt.Info.ID,
-		Sender:    evt.Info.Sender,
-		Message:   evt.Message,
+	var quotedMsg *senders.QuotedMessage
+	if !resp.NoQuote && chat == evt.Info.Chat {
+		quotedMsg = &senders.QuotedMessage{
+			MessageID: evt.Info.ID,
+			Sender:    evt.Info.Sender,
+			Message:   evt.Message,
+		}
 	}
 
-	// Process message using command handler with context (for quoted replies)
-	response := bot.commandHandler.ProcessMessageWithContext(messageText, evt, evt.Info.Sender)
-	if response != "" {
-		if bot.sender != nil && bot.sender.Text != nil {
-			bot.sender.Text.SendTextWithQuote(evt.Info.Sender, response, quotedMsg)
-		} else {
-			bot.sendMessage(evt.Info.Sender, response)
+	if resp.Reaction != "" {
+		if bot.sender != nil && bot.sender.Reaction != nil {
+			if _, err := bot.sender.Reaction.SendReaction(evt.Info.Chat, evt.Info.Sender, evt.Info.ID, resp.Reaction); err != nil {
+				log.Printf("Failed to send reaction: %v", err)
+			}
 		}
 	}
 
-	// Broadcast incoming messages over API websocket if configured
-	if bot.apiServer != nil {
-		msg := api.WSMessage{From: evt.Info.Sender.String(), Text: messageText, Event: "message", UserID: bot.InstanceUserID()}
-		bot.apiServer.BroadcastIncoming(msg)
+	for _, m := range resp.Messages {
+		if err := bot.sendResponseMessage(chat, m, quotedMsg); err != nil {
+			log.Printf("Failed to send %s response: %v", m.Kind, err)
+		}
 	}
 }
 
+// sendResponseMessage sends a single message of a response through the senders
+func (bot *WhatsAppBot) sendResponseMessage(to types.JID, m response.Message, quotedMsg *senders.QuotedMessage) error {
+	if bot.sender == nil {
+		if m.Kind != response.KindText {
+			return fmt.Errorf("senders not configured")
+		}
+		bot.sendMessage(to, m.Text)
+		return nil
+	}
+
+	var err error
+	switch m.Kind {
+	case response.KindText:
+		if bot.sender.Text == nil {
+			bot.sendMessage(to, m.Text)
+			return nil
+		}
+		_, err = bot.sender.Text.SendTextWithQuote(to, m.Text, quotedMsg)
+	case response.KindImage:
+		if bot.sender.Image == nil {
+			return fmt.Errorf("image sender not configured")
+		}
+		_, err = bot.sender.Image.SendImageWithQuote(to, m.Path, m.Caption, quotedMsg)
+	case response.KindVideo:
+		if bot.sender.Video == nil {
+			return fmt.Errorf("video sender not configured")
+		}
+		_, err = bot.sender.Video.SendVideoWithQuote(to, m.Path, m.Caption, quotedMsg)
+	case response.KindDocument:
+		if bot.sender.Document == nil {
+			return fmt.Errorf("document sender not configured")
+		}
+		_, err = bot.sender.Document.SendDocumentWithQuote(to, m.Path, m.Title, quotedMsg)
+	default:
+		return fmt.Errorf("unknown message kind %q", m.Kind)
+	}
+	return err
+}
+
 // sendMessage sends a text message to specified JID
 func (bot *WhatsAppBot) sendMessage(to types.JID, text string) {
 	// Convert to user JID (remove device part) if needed
@@ -233,7 +432,23 @@ func (bot *WhatsAppBot) sendMessage(to types.JID, text string) {
 
 // Disconnect gracefully disconnects the bot
 func (bot *WhatsAppBot) Disconnect() {
+	bot.publish(api.EventBot, api.BotPayload{State: "stopping"})
 	bot.client.Disconnect()
+	if bot.mediaStore != nil {
+		bot.mediaStore.Close()
+	}
+	if bot.webhooks != nil {
+		bot.webhooks.Close()
+	}
+	if bot.journal != nil {
+		bot.journal.Close()
+	}
+	bot.db.Close()
+}
+
+// MessageMetrics returns counters of handled messages
+func (bot *WhatsAppBot) MessageMetrics() MessageMetricsSnapshot {
+	return bot.metrics.Snapshot()
 }
 
 // InstanceUserID returns configured instance user id
@@ -0,0 +1,231 @@
+package bot
+
+import (
+	"strings"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+
+	"whatsappBotGo/src/api"
+	"whatsappBotGo/src/apikeys"
+	"whatsappBotGo/src/journal"
+	"whatsappBotGo/src/senders"
+	"whatsappBotGo/src/webhook"
+	"whatsappBotGo/src/whats"
+)
+
+// publish broadcasts an event to API websocket clients and webhooks
+func (bot *WhatsAppBot) publish(t api.EventType, payload any) {
+	if bot.apiServer != nil {
+		bot.apiServer.Publish(t, payload)
+	}
+}
+
+// Journal returns the event journal, or nil if it is disabled
+func (bot *WhatsAppBot) Journal() *journal.Store {
+	return bot.journal
+}
+
+// APIKeys returns the API key store, or nil if authentication is disabled
+func (bot *WhatsAppBot) APIKeys() *apikeys.Store {
+	return bot.apiKeys
+}
+
+// Webhooks returns the webhook outbox, or nil if no webhook is configured
+func (bot *WhatsAppBot) Webhooks() *webhook.Outbox {
+	return bot.webhooks
+}
+
+// broadcastIncoming sends an incoming message to API websocket clients
+func (bot *WhatsAppBot) broadcastIncoming(msg *MessageContext) {
+	t := api.EventMessage
+	switch msg.Incoming.Type {
+	case whats.TypeEdit:
+		t = api.EventMessageEdit
+	case whats.TypeDelete:
+		t = api.EventMessageDelete
+	case whats.TypeReaction:
+		t = api.EventReaction
+	}
+	bot.publish(t, msg.Incoming)
+}
+
+// broadcastSent sends a message sent through the senders, by the bot or the
+// REST API, to API websocket clients
+func (bot *WhatsAppBot) broadcastSent(sent senders.SentMessage) {
+	if bot.apiServer == nil {
+		return
+	}
+	bot.publish(api.EventMessageSent, api.SentPayload{Message: bot.sentMessage(sent), Result: sent.Result})
+}
+
+// broadcastQR forwards login progress from the QR channel
+func (bot *WhatsAppBot) broadcastQR(evt whatsmeow.QRChannelItem) {
+	switch {
+	case evt.Event == whatsmeow.QRChannelEventCode:
+		bot.publish(api.EventQR, api.QRPayload{Code: evt.Code, TimeoutMs: evt.Timeout.Milliseconds()})
+	case evt == whatsmeow.QRChannelSuccess:
+		// Reported by the PairSuccess event
+	case evt == whatsmeow.QRChannelTimeout:
+		bot.publish(api.EventPairing, api.PairingPayload{Status: "timeout"})
+	default:
+		payload := api.PairingPayload{Status: "error", Error: strings.TrimPrefix(evt.Event, "err-")}
+		if evt.Error != nil {
+			payload.Error = evt.Error.Error()
+		}
+		bot.publish(api.EventPairing, payload)
+	}
+}
+
+// subscribeWebSocket forwards WhatsApp events to API websocket clients.
+// Messages and receipts are broadcast by their own handlers once processed.
+func (bot *WhatsAppBot) subscribeWebSocket() {
+	bus := bot.events
+	connection := func(state, reason string) error {
+		bot.publish(api.EventConnection, api.ConnectionPayload{State: state, Reason: reason})
+		return nil
+	}
+
+	whats.On(bus, "websocket", func(evt *events.Connected) error { return connection("connected", "") })
+	whats.On(bus, "websocket", func(evt *events.Disconnected) error { return connection("disconnected", "") })
+	whats.On(bus, "websocket", func(evt *events.StreamReplaced) error { return connection("stream_replaced", "") })
+	whats.On(bus, "websocket", func(evt *events.ClientOutdated) error { return connection("client_outdated", "") })
+	whats.On(bus, "websocket", func(evt *events.KeepAliveTimeout) error { return connection("keepalive_timeout", "") })
+	whats.On(bus, "websocket", func(evt *events.KeepAliveRestored) error { return connection("keepalive_restored", "") })
+	whats.On(bus, "websocket", func(evt *events.LoggedOut) error { return connection("logged_out", evt.Reason.String()) })
+	whats.On(bus, "websocket", func(evt *events.ConnectFailure) error {
+		return connection("connect_failure", strings.TrimSpace(evt.Reason.String()+" "+evt.Message))
+	})
+	whats.On(bus, "websocket", func(evt *events.TemporaryBan) error {
+		expires := time.Now().Add(evt.Expire).UTC()
+		bot.publish(api.EventConnection, api.ConnectionPayload{State: "temporary_ban", Reason: evt.Code.String(), Expires: &expires})
+		return nil
+	})
+
+	whats.On(bus, "websocket", func(evt *events.PairSuccess) error {
+		bot.publish(api.EventPairing, api.PairingPayload{
+			Status: "success", JID: evt.ID.String(), Platform: evt.Platform, BusinessName: evt.BusinessName,
+		})
+		return nil
+	})
+	whats.On(bus, "websocket", func(evt *events.PairError) error {
+		bot.publish(api.EventPairing, api.PairingPayload{
+			Status: "error", JID: evt.ID.String(), Platform: evt.Platform, BusinessName: evt.BusinessName, Error: evt.Error.Error(),
+		})
+		return nil
+	})
+
+	whats.On(bus, "websocket", func(evt *events.Receipt) error {
+		receiptType := string(evt.Type)
+		if evt.Type == types.ReceiptTypeDelivered {
+			receiptType = "delivered"
+		}
+		bot.publish(api.EventReceipt, api.ReceiptPayload{
+			Chat: evt.Chat.String(), Sender: evt.Sender.String(), IsGroup: evt.IsGroup, IsFromMe: evt.IsFromMe,
+			MessageIDs: evt.MessageIDs, Type: receiptType, Timestamp: evt.Timestamp,
+		})
+		return nil
+	})
+	whats.On(bus, "websocket", func(evt *events.UndecryptableMessage) error {
+		bot.publish(api.EventUndecryptable, api.UndecryptablePayload{
+			ID: evt.Info.ID, Chat: evt.Info.Chat.String(), Sender: evt.Info.Sender.String(),
+			Timestamp: evt.Info.Timestamp, IsGroup: evt.Info.IsGroup, IsUnavailable: evt.IsUnavailable,
+		})
+		return nil
+	})
+
+	whats.On(bus, "websocket", func(evt *events.Presence) error {
+		payload := api.PresencePayload{JID: evt.From.String(), Available: !evt.Unavailable}
+		if !evt.LastSeen.IsZero() {
+			payload.LastSeen = &evt.LastSeen
+		}
+		bot.publish(api.EventPresence, payload)
+		return nil
+	})
+	whats.On(bus, "websocket", func(evt *events.ChatPresence) error {
+		bot.publish(api.EventChatPresence, api.ChatPresencePayload{
+			Chat: evt.Chat.String(), Sender: evt.Sender.String(), IsGroup: evt.IsGroup,
+			State: string(evt.State), Media: string(evt.Media),
+		})
+		return nil
+	})
+
+	whats.On(bus, "websocket", func(evt *events.GroupInfo) error {
+		bot.publish(api.EventGroup, groupPayload(evt))
+		return nil
+	})
+	whats.On(bus, "websocket", func(evt *events.JoinedGroup) error {
+		payload := api.GroupJoinedPayload{JID: evt.JID.String(), Name: evt.Name, Reason: evt.Reason}
+		if evt.Sender != nil {
+			payload.Sender = evt.Sender.String()
+		}
+		bot.publish(api.EventGroupJoined, payload)
+		return nil
+	})
+
+	call := func(meta types.BasicCallMeta, state, media, reason string) error {
+		payload := api.CallPayload{
+			CallID: meta.CallID, From: meta.From.String(), Timestamp: meta.Timestamp,
+			State: state, Media: media, Reason: reason,
+		}
+		if !meta.GroupJID.IsEmpty() {
+			payload.GroupJID = meta.GroupJID.String()
+		}
+		bot.publish(api.EventCall, payload)
+		return nil
+	}
+	whats.On(bus, "websocket", func(evt *events.CallOffer) error { return call(evt.BasicCallMeta, "offer", "", "") })
+	whats.On(bus, "websocket", func(evt *events.CallOfferNotice) error {
+		return call(evt.BasicCallMeta, "offer", evt.Media, "")
+	})
+	whats.On(bus, "websocket", func(evt *events.CallAccept) error { return call(evt.BasicCallMeta, "accept", "", "") })
+	whats.On(bus, "websocket", func(evt *events.CallReject) error { return call(evt.BasicCallMeta, "reject", "", "") })
+	whats.On(bus, "websocket", func(evt *events.CallTerminate) error {
+		return call(evt.BasicCallMeta, "terminate", "", evt.Reason)
+	})
+
+	whats.On(bus, "websocket", func(evt *events.HistorySync) error {
+		bot.publish(api.EventHistorySync, api.HistorySyncPayload{
+			Type: evt.Data.GetSyncType().String(), Conversations: len(evt.Data.GetConversations()),
+		})
+		return nil
+	})
+}
+
+// groupPayload lists the changes of a group info event
+func groupPayload(evt *events.GroupInfo) api.GroupPayload {
+	payload := api.GroupPayload{
+		JID:       evt.JID.String(),
+		Timestamp: evt.Timestamp,
+		Join:      jidStrings(evt.Join),
+		Leave:     jidStrings(evt.Leave),
+		Promote:   jidStrings(evt.Promote),
+		Demote:    jidStrings(evt.Demote),
+	}
+	if evt.Sender != nil {
+		payload.Sender = evt.Sender.String()
+	}
+	if evt.Name != nil {
+		payload.Name = &evt.Name.Name
+	}
+	if evt.Topic != nil {
+		payload.Topic = &evt.Topic.Topic
+	}
+	if evt.Locked != nil {
+		payload.Locked = &evt.Locked.IsLocked
+	}
+	if evt.Announce != nil {
+		payload.Announce = &evt.Announce.IsAnnounce
+	}
+	return payload
+}
+
+func jidStrings(jids []types.JID) []string {
+	var out []string
+	for _, jid := range jids {
+		out = append(out, jid.String())
+	}
+	return out
+}
@@ -1,8 +1,17 @@
 package bot
 
 import (
+	"errors"
+	"fmt"
+	"log"
 	"strings"
 
+	"whatsappBotGo/src/bot/args"
+	"whatsappBotGo/src/bot/response"
+	"whatsappBotGo/src/bot/roles"
+	"whatsappBotGo/src/bot/settings"
+	"whatsappBotGo/src/commands/system"
+	"whatsappBotGo/src/functions"
 	"whatsappBotGo/src/handlers"
 	"whatsappBotGo/src/senders"
 
@@ -14,22 +23,143 @@ import (
 type Command interface {
 	Name() string
 	Description() string
-	Execute(args []string, sender types.JID) string
-	ExecuteWithContext(args []string, evt *events.Message, sender types.JID) string
+	Execute(args *args.Args, sender types.JID) string
+	ExecuteWithContext(args *args.Args, evt *events.Message, sender types.JID) string
+}
+
+// ResponseCommand is a command that answers with a rich response
+// (media, reactions, several messages) instead of a single string
+type ResponseCommand interface {
+	Name() string
+	Description() string
+	// Respond runs the command; evt is nil when there is no event context
+	Respond(args *args.Args, evt *events.Message, sender types.JID) *response.Response
+}
+
+// Categorized is implemented by commands that belong to a help category
+// such as "system", "fun" or "admin"
+type Categorized interface {
+	Category() string
+}
+
+// UsageProvider is implemented by commands that document their syntax
+type UsageProvider interface {
+	Usage() string
+}
+
+// ExampleProvider is implemented by commands that provide usage examples
+type ExampleProvider interface {
+	Examples() []string
+}
+
+// AliasProvider is implemented by commands reachable under other names
+type AliasProvider interface {
+	Aliases() []string
+}
+
+// Restricted is implemented by commands that are not available to everyone
+type Restricted interface {
+	Allowed(evt *events.Message, sender types.JID) bool
+}
+
+// TextCommandAdapter lets a string-returning Command be used as a ResponseCommand
+type TextCommandAdapter struct {
+	Command
+}
+
+// AdaptCommand wraps a string-returning command as a ResponseCommand
+func AdaptCommand(cmd Command) ResponseCommand {
+	return TextCommandAdapter{Command: cmd}
+}
+
+// Respond runs the wrapped command and sends its output as a quoted text
+func (t TextCommandAdapter) Respond(args *args.Args, evt *events.Message, sender types.JID) *response.Response {
+	if evt == nil {
+		return response.Text(t.Execute(args, sender))
+	}
+	return response.Text(t.ExecuteWithContext(args, evt, sender))
+}
+
+// Unwrap returns the wrapped string-returning command
+func (t TextCommandAdapter) Unwrap() Command {
+	return t.Command
+}
+
+// unwrapCommand returns the original command so optional interfaces such as
+// Categorized can be checked on commands registered through the adapter
+func unwrapCommand(cmd ResponseCommand) any {
+	if a, ok := cmd.(TextCommandAdapter); ok {
+		return a.Unwrap()
+	}
+	return cmd
+}
+
+// CommandRequest is a command parsed from a message
+type CommandRequest struct {
+	Name    string          // As typed, without the prefix
+	Command ResponseCommand // nil if no command is registered under Name
+	Args    *args.Args
+	Err     error // Set when the message could not be parsed; Name is then empty
 }
 
 // CommandHandler manages all bot commands
 type CommandHandler struct {
-	commands         map[string]Command
+	commands         map[string]ResponseCommand
+	aliases          map[string]string // alias -> command name
 	autoReplyHandler *handlers.AutoReplyHandler
+	permissions      *Permissions
+	prefixes         []string
+	settings         *settings.Store
+	suggestDirect    bool // Default for "did you mean" replies in direct chats
+	suggestGroups    bool // Default for "did you mean" replies in groups
 }
 
 // NewCommandHandler creates a new command handler
 func NewCommandHandler() *CommandHandler {
-	return &CommandHandler{
-		commands:         make(map[string]Command),
+	ch := &CommandHandler{
+		commands:         make(map[string]ResponseCommand),
+		aliases:          make(map[string]string),
 		autoReplyHandler: handlers.NewAutoReplyHandler(),
+		suggestDirect:    functions.GetEnvBool("SUGGEST_COMMANDS", true),
+		suggestGroups:    functions.GetEnvBool("SUGGEST_COMMANDS_IN_GROUPS", false),
+	}
+	ch.SetPrefixes(strings.Split(functions.GetEnv("COMMAND_PREFIXES", "/"), ",")...)
+	return ch
+}
+
+// SetPrefixes sets the prefixes that mark a message as a command.
+// The first prefix is the one shown in help texts.
+func (ch *CommandHandler) SetPrefixes(prefixes ...string) {
+	ch.prefixes = ch.prefixes[:0]
+	for _, p := range prefixes {
+		if p = strings.TrimSpace(p); p != "" {
+			ch.prefixes = append(ch.prefixes, p)
+		}
+	}
+	if len(ch.prefixes) == 0 {
+		ch.prefixes = []string{"/"}
+	}
+}
+
+// Prefix returns the primary command prefix
+func (ch *CommandHandler) Prefix() string {
+	return ch.prefixes[0]
+}
+
+// matchPrefix returns the longest configured prefix message starts with
+func (ch *CommandHandler) matchPrefix(message string) (string, bool) {
+	match := ""
+	for _, p := range ch.prefixes {
+		if strings.HasPrefix(message, p) && len(p) > len(match) {
+			match = p
+		}
 	}
+	return match, match != ""
+}
+
+// SetChatSettings sets the store for per-chat settings such as suggestions
+func (ch *CommandHandler) SetChatSettings(s *settings.Store) {
+	ch.settings = s
 }
 
 // SetSenders sets the senders for the auto reply handler
@@ -37,69 +167,137 @@ func (ch *CommandHandler) SetSenders(s *senders.Senders) {
 	ch.autoReplyHandler.SetSenders(s)
 }
 
-// RegisterCommand registers a new command
-func (ch *CommandHandler) RegisterCommand(cmd Command) {
-	ch.commands[cmd.Name()] = cmd
+// SetPermissions sets the resolver used to check command roles
+func (ch *CommandHandler) SetPermissions(p *Permissions) {
+	ch.permissions = p
 }
 
-// handleNonCommand handles messages that are not commands
-func (ch *CommandHandler) handleNonCommand(message string, sender types.JID) string {
-	return ch.autoReplyHandler.ProcessMessage(message, sender)
+// RoleOf returns the effective role of sender for the given message
+func (ch *CommandHandler) RoleOf(evt *events.Message, sender types.JID) roles.Role {
+	return ch.permissions.RoleOf(evt, sender)
 }
 
-// ProcessMessage processes incoming messages and executes commands
-func (ch *CommandHandler) ProcessMessage(message string, sender types.JID) string {
-	originalMessage := strings.TrimSpace(message)
-	message = strings.ToLower(originalMessage)
+// RegisterCommand registers a new string-returning command
+func (ch *CommandHandler) RegisterCommand(cmd Command) {
+	ch.RegisterResponseCommand(AdaptCommand(cmd))
+}
 
-	// Handle non-command messages
-	if !strings.HasPrefix(message, "/") {
-		return ch.handleNonCommand(originalMessage, sender)
+// RegisterResponseCommand registers a new command that returns a rich response.
+// Command names and aliases are given without a prefix.
+func (ch *CommandHandler) RegisterResponseCommand(cmd ResponseCommand) {
+	name := strings.ToLower(cmd.Name())
+	ch.commands[name] = cmd
+	if a, ok := unwrapCommand(cmd).(AliasProvider); ok {
+		for _, alias := range a.Aliases() {
+			ch.RegisterAlias(alias, name)
+		}
 	}
+}
 
-	// Parse command and arguments
-	parts := strings.Fields(message)
-	if len(parts) == 0 {
-		return "🤔 I didn't understand that. Type /help for available commands."
+// RegisterAlias makes a registered command reachable under another name.
+// Aliases never shadow the name of another command.
+func (ch *CommandHandler) RegisterAlias(alias, name string) {
+	alias, name = strings.ToLower(alias), strings.ToLower(name)
+	if _, exists := ch.commands[alias]; exists {
+		log.Printf("Ignoring alias %q for %q: a command with that name exists", alias, name)
+		return
 	}
+	ch.aliases[alias] = name
+}
 
-	commandName := parts[0]
-	args := parts[1:]
+// lookup finds a command by name or alias
+func (ch *CommandHandler) lookup(name string) (ResponseCommand, bool) {
+	if cmd, ok := ch.commands[name]; ok {
+		return cmd, true
+	}
+	if target, ok := ch.aliases[name]; ok {
+		cmd, ok := ch.commands[target]
+		return cmd, ok
+	}
+	return nil, false
+}
 
-	// Find and execute command
-	if cmd, exists := ch.commands[commandName]; exists {
-		return cmd.Execute(args, sender)
+// canRun reports whether sender may run cmd
+func (ch *CommandHandler) canRun(cmd ResponseCommand, evt *events.Message, sender types.JID) bool {
+	info := unwrapCommand(cmd)
+	if r, ok := info.(RoleRequirer); ok && ch.RoleOf(evt, sender) < r.RequiredRole() {
+		return false
 	}
-	return "" //return nothing if command not found
+	if r, ok := info.(Restricted); ok {
+		return r.Allowed(evt, sender)
+	}
+	return true
 }
 
-// ProcessMessageWithContext processes incoming messages and executes commands with event context
-func (ch *CommandHandler) ProcessMessageWithContext(message string, evt *events.Message, sender types.JID) string {
-	originalMessage := strings.TrimSpace(message)
-	message = strings.ToLower(originalMessage)
+// handleNonCommand handles messages that are not commands
+func (ch *CommandHandler) handleNonCommand(message string, sender types.JID) *response.Response {
+	return response.Text(ch.autoReplyHandler.ProcessMessage(message, sender))
+}
 
-	// Handle non-command messages
-	if !strings.HasPrefix(message, "/") {
-		return ch.handleNonCommand(originalMessage, sender)
+// Parse parses message as a command, nil if it doesn't start with a prefix
+func (ch *CommandHandler) Parse(message string) *CommandRequest {
+	message = strings.TrimSpace(message)
+	prefix, isCommand := ch.matchPrefix(message)
+	if !isCommand {
+		return nil
 	}
 
-	// Parse command and arguments
-	parts := strings.Fields(message)
-	if len(parts) == 0 {
-		return "🤔 I didn't understand that. Type /help for available commands."
+	parsed, err := args.Parse(message[len(prefix):])
+	if err == nil && parsed.Command == "" {
+		err = errors.New("no command name")
 	}
+	if err != nil {
+		return &CommandRequest{Err: err}
+	}
+	cmd, _ := ch.lookup(parsed.Command)
+	return &CommandRequest{Name: parsed.Command, Command: cmd, Args: parsed}
+}
 
-	commandName := parts[0]
-	args := parts[1:]
-
-	// Find and execute command with context
-	if cmd, exists := ch.commands[commandName]; exists {
-		return cmd.ExecuteWithContext(args, evt, sender)
+// Respond answers a message: it runs the command of req, or the auto reply
+// handler when req is nil, and explains commands that can't be run.
+// Permissions are checked before, by PermissionMiddleware.
+func (ch *CommandHandler) Respond(req *CommandRequest, message string, evt *events.Message, sender types.JID) *response.Response {
+	switch {
+	case req == nil:
+		return ch.handleNonCommand(message, sender)
+	case errors.Is(req.Err, args.ErrUnterminatedQuote):
+		return response.Text("🤔 Looks like a quote was left open. Close it and try again.")
+	case req.Err != nil:
+		return response.Text(fmt.Sprintf("🤔 I didn't understand that. Type %shelp for available commands.", ch.Prefix()))
+	case req.Command == nil:
+		return response.Text(ch.suggest(req.Name, evt, sender))
 	}
-	return "" //return nothing if command not found
+	return req.Command.Respond(req.Args, evt, sender)
 }
 
 // GetAllCommands returns all registered commands
-func (ch *CommandHandler) GetAllCommands() map[string]Command {
+func (ch *CommandHandler) GetAllCommands() map[string]ResponseCommand {
 	return ch.commands
 }
+
+// HelpEntries describes the commands sender is allowed to run
+func (ch *CommandHandler) HelpEntries(evt *events.Message, sender types.JID) []system.HelpEntry {
+	entries := make([]system.HelpEntry, 0, len(ch.commands))
+	for _, cmd := range ch.commands {
+		if !ch.canRun(cmd, evt, sender) {
+			continue
+		}
+
+		entry := system.HelpEntry{Name: cmd.Name(), Description: cmd.Description()}
+		info := unwrapCommand(cmd)
+		if c, ok := info.(Categorized); ok {
+			entry.Category = c.Category()
+		}
+		if u, ok := info.(UsageProvider); ok {
+			entry.Usage = u.Usage()
+		}
+		if e, ok := info.(ExampleProvider); ok {
+			entry.Examples = e.Examples()
+		}
+		if a, ok := info.(AliasProvider); ok {
+			entry.Aliases = a.Aliases()
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
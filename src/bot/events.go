@@ -0,0 +1,87 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+
+	"go.mau.fi/whatsmeow/types/events"
+
+	"whatsappBotGo/src/whats"
+)
+
+// subscribeEvents registers the bot's own handlers on the event bus
+func (bot *WhatsAppBot) subscribeEvents() {
+	bus := bot.events
+
+	whats.On(bus, "messages", func(evt *events.Message) error {
+		bot.handleMessage(evt)
+		return nil
+	})
+	whats.On(bus, "receipts", func(evt *events.Receipt) error {
+		bot.handleReceipt(evt)
+		return nil
+	})
+	whats.On(bus, "group-admins", func(evt *events.GroupInfo) error {
+		// Admin promotions and demotions change who may run group-admin commands
+		bot.groupAdmins.Invalidate(evt.JID)
+		return nil
+	})
+
+	// Connection lifecycle
+	whats.On(bus, "log", func(evt *events.Connected) error {
+		fmt.Println("Connected to WhatsApp")
+		return nil
+	})
+	whats.On(bus, "log", func(evt *events.Disconnected) error {
+		fmt.Println("Disconnected from WhatsApp")
+		return nil
+	})
+	whats.On(bus, "log", func(evt *events.LoggedOut) error {
+		log.Printf("Logged out from WhatsApp (reason %v); delete session.db and scan the QR code again", evt.Reason)
+		return nil
+	})
+	whats.On(bus, "log", func(evt *events.StreamReplaced) error {
+		log.Printf("Another client connected with this session; this one was disconnected")
+		return nil
+	})
+	whats.On(bus, "log", func(evt *events.TemporaryBan) error {
+		log.Printf("WhatsApp banned this account temporarily: %s", evt)
+		return nil
+	})
+	whats.On(bus, "log", func(evt *events.ConnectFailure) error {
+		log.Printf("Failed to connect to WhatsApp: %v %s", evt.Reason, evt.Message)
+		return nil
+	})
+
+	// Chats and groups
+	whats.On(bus, "log", func(evt *events.Presence) error {
+		if evt.Unavailable {
+			fmt.Printf("%s went offline\n", evt.From)
+		} else {
+			fmt.Printf("%s is online\n", evt.From)
+		}
+		return nil
+	})
+	whats.On(bus, "log", func(evt *events.CallOffer) error {
+		fmt.Printf("Incoming call from %s\n", evt.From)
+		return nil
+	})
+	whats.On(bus, "log", func(evt *events.JoinedGroup) error {
+		fmt.Printf("Joined group %s (%s)\n", evt.JID, evt.Name)
+		return nil
+	})
+	whats.On(bus, "log", func(evt *events.HistorySync) error {
+		fmt.Printf("Received history sync with %d conversations\n", len(evt.Data.GetConversations()))
+		return nil
+	})
+	whats.On(bus, "log", func(evt *events.UndecryptableMessage) error {
+		log.Printf("Could not decrypt message %s from %s", evt.Info.ID, evt.Info.Sender)
+		return nil
+	})
+}
+
+// Events returns the event bus; subscribe with whats.On to handle any
+// whatsmeow event without touching the bot
+func (bot *WhatsAppBot) Events() *whats.Events {
+	return bot.events
+}
@@ -0,0 +1,63 @@
+package bot
+
+import (
+	"log"
+
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+
+	"whatsappBotGo/src/history"
+	"whatsappBotGo/src/senders"
+	"whatsappBotGo/src/whats"
+)
+
+// HistoryMiddleware records every message that reaches it in the message history
+func HistoryMiddleware(store *history.Store) MessageMiddleware {
+	return func(next MessageHandlerFunc) MessageHandlerFunc {
+		return func(msg *MessageContext) {
+			status := history.StatusReceived
+			if msg.Incoming.IsFromMe {
+				status = history.StatusSent // Sent from another device of this account
+			}
+			if err := store.Record(msg.Incoming, status); err != nil {
+				log.Printf("Failed to record message %s: %v", msg.Incoming.ID, err)
+			}
+			next(msg)
+		}
+	}
+}
+
+// sentMessage normalizes a message sent through the senders
+func (bot *WhatsAppBot) sentMessage(sent senders.SentMessage) *whats.IncomingMessage {
+	own := sent.Response.Sender
+	if own.IsEmpty() && bot.client.Store.ID != nil {
+		own = bot.client.Store.ID.ToNonAD()
+	}
+
+	evt := &events.Message{
+		Info: types.MessageInfo{
+			MessageSource: types.MessageSource{
+				Chat:     sent.Result.Recipient,
+				Sender:   own,
+				IsFromMe: true,
+				IsGroup:  sent.Result.Recipient.Server == types.GroupServer,
+			},
+			ID:        sent.Result.MessageID,
+			Timestamp: sent.Result.Timestamp,
+		},
+		Message: sent.Message,
+	}
+	return whats.ParseMessage(evt)
+}
+
+// recordSent records a message sent through the senders in the message history
+func (bot *WhatsAppBot) recordSent(sent senders.SentMessage) {
+	if err := bot.history.Record(bot.sentMessage(sent), history.StatusSent); err != nil {
+		log.Printf("Failed to record sent message %s: %v", sent.Result.MessageID, err)
+	}
+}
+
+// History returns the message history store
+func (bot *WhatsAppBot) History() *history.Store {
+	return bot.history
+}
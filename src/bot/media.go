@@ -0,0 +1,57 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"whatsappBotGo/src/media"
+	"whatsappBotGo/src/whats"
+)
+
+// mediaDownloadTimeout bounds a single attachment download
+const mediaDownloadTimeout = 2 * time.Minute
+
+// parseMediaTypes parses a comma-separated list of message types to download
+func parseMediaTypes(list string) map[whats.MessageType]bool {
+	types := make(map[whats.MessageType]bool)
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.ToLower(strings.TrimSpace(item)); item != "" {
+			types[whats.MessageType(item)] = true
+		}
+	}
+	return types
+}
+
+// wantsMedia reports whether the attachment of msg should be downloaded
+func (bot *WhatsAppBot) wantsMedia(msg *whats.IncomingMessage) bool {
+	return bot.mediaStore != nil && msg.HasMedia() && bot.mediaTypes[msg.Type]
+}
+
+// downloadMedia fetches the attachment of msg into the media store and sets
+// msg.Media.ID on success
+func (bot *WhatsAppBot) downloadMedia(msg *whats.IncomingMessage) error {
+	if limit := bot.mediaStore.MaxFileSize(); limit > 0 && int64(msg.Media.FileLength) > limit {
+		return fmt.Errorf("%w (%d bytes)", media.ErrTooLarge, msg.Media.FileLength)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), mediaDownloadTimeout)
+	defer cancel()
+	data, err := bot.client.Download(ctx, msg.Downloadable())
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", msg.Type, err)
+	}
+
+	info, err := bot.mediaStore.Save(data, msg.Media.Mimetype, msg.Media.FileName)
+	if err != nil {
+		return err
+	}
+	msg.Media.ID = info.ID
+	return nil
+}
+
+// MediaStore returns the store of downloaded attachments, nil if disabled
+func (bot *WhatsAppBot) MediaStore() *media.Store {
+	return bot.mediaStore
+}
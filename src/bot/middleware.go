@@ -0,0 +1,226 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+
+	"whatsappBotGo/src/bot/response"
+	"whatsappBotGo/src/bot/roles"
+	"whatsappBotGo/src/whats"
+)
+
+// MessageContext is an incoming message passing through the middleware chain.
+// Middlewares may rewrite Text or Event before calling the next handler.
+type MessageContext struct {
+	Event    *events.Message
+	Incoming *whats.IncomingMessage // Normalized form of Event
+	Text     string                 // Text or media caption, empty if there is none
+
+	bot       *WhatsAppBot
+	completed bool // Set once the message reached the end of the chain
+
+	parsed      bool
+	command     *CommandRequest
+	commandText string // Text the command was parsed from
+}
+
+// expectsReply reports whether the message is answered by a command or an
+// auto reply: text and captions from others, except edits
+func (m *MessageContext) expectsReply() bool {
+	return !m.Event.Info.IsFromMe && m.Event.Message != nil && m.Text != "" && m.Incoming.Type != whats.TypeEdit
+}
+
+// Command returns the command the message invokes, nil if it isn't a
+// command. It is parsed from Text on first use, and again if a middleware
+// rewrote Text since.
+func (m *MessageContext) Command() *CommandRequest {
+	if m.bot == nil || !m.expectsReply() {
+		return nil
+	}
+	if !m.parsed || m.commandText != m.Text {
+		m.command, m.commandText, m.parsed = m.bot.commandHandler.Parse(m.Text), m.Text, true
+	}
+	return m.command
+}
+
+// Reply sends resp in response to the message, e.g. from a middleware that
+// stops the chain
+func (m *MessageContext) Reply(resp *response.Response) {
+	if m.bot != nil {
+		m.bot.sendResponse(m.Event, resp)
+	}
+}
+
+// MessageHandlerFunc handles an incoming message
+type MessageHandlerFunc func(msg *MessageContext)
+
+// MessageMiddleware wraps message handling. A middleware stops the chain by
+// not calling next.
+type MessageMiddleware func(next MessageHandlerFunc) MessageHandlerFunc
+
+// Use appends middlewares to the message chain; the first one added runs first
+func (bot *WhatsAppBot) Use(mw ...MessageMiddleware) {
+	bot.mwMu.Lock()
+	defer bot.mwMu.Unlock()
+	bot.middlewares = append(bot.middlewares, mw...)
+}
+
+// messageChain wraps processMessage in the registered middlewares
+func (bot *WhatsAppBot) messageChain() MessageHandlerFunc {
+	bot.mwMu.RLock()
+	defer bot.mwMu.RUnlock()
+
+	handler := MessageHandlerFunc(bot.processMessage)
+	for i := len(bot.middlewares) - 1; i >= 0; i-- {
+		handler = bot.middlewares[i](handler)
+	}
+	return handler
+}
+
+// RecoverMiddleware keeps a panic in one message from crashing the bot
+func RecoverMiddleware(next MessageHandlerFunc) MessageHandlerFunc {
+	return func(msg *MessageContext) {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("Panic while handling message %s: %v\n%s", msg.Event.Info.ID, r, debug.Stack())
+			}
+		}()
+		next(msg)
+	}
+}
+
+// LoggingMiddleware prints every incoming message
+func LoggingMiddleware(next MessageHandlerFunc) MessageHandlerFunc {
+	return func(msg *MessageContext) {
+		if !msg.Event.Info.IsFromMe {
+			if kind := msg.Incoming.Type; kind != whats.TypeText {
+				fmt.Printf("Received %s from %s: %s\n", kind, msg.Event.Info.Sender, msg.Text)
+			} else {
+				fmt.Printf("Received message from %s: %s\n", msg.Event.Info.Sender, msg.Text)
+			}
+		}
+		next(msg)
+	}
+}
+
+// BlacklistMiddleware drops messages from the given users and chats
+func BlacklistMiddleware(jids ...types.JID) MessageMiddleware {
+	blocked := make(map[types.JID]bool, len(jids))
+	for _, jid := range jids {
+		blocked[jid.ToNonAD()] = true
+	}
+
+	return func(next MessageHandlerFunc) MessageHandlerFunc {
+		return func(msg *MessageContext) {
+			info := msg.Event.Info
+			for _, jid := range []types.JID{info.Sender, info.SenderAlt, info.Chat} {
+				if !jid.IsEmpty() && blocked[jid.ToNonAD()] {
+					return
+				}
+			}
+			next(msg)
+		}
+	}
+}
+
+// Maintenance is a middleware that, while enabled, answers everyone below
+// an exempt role with a maintenance notice instead of handling the message
+type Maintenance struct {
+	enabled    atomic.Bool
+	resolver   roles.Resolver
+	exemptRole roles.Role
+
+	mu       sync.Mutex
+	notified map[types.JID]bool // Chats already told about the current maintenance
+}
+
+// NewMaintenance creates a maintenance switch; resolver is used to let
+// admins keep using the bot
+func NewMaintenance(enabled bool, resolver roles.Resolver) *Maintenance {
+	m := &Maintenance{resolver: resolver, exemptRole: roles.RoleAdmin, notified: make(map[types.JID]bool)}
+	m.enabled.Store(enabled)
+	return m
+}
+
+// InMaintenance reports whether maintenance mode is on
+func (m *Maintenance) InMaintenance() bool {
+	return m.enabled.Load()
+}
+
+// SetMaintenance turns maintenance mode on or off
+func (m *Maintenance) SetMaintenance(enabled bool) {
+	m.enabled.Store(enabled)
+	m.mu.Lock()
+	m.notified = make(map[types.JID]bool)
+	m.mu.Unlock()
+}
+
+// Middleware implements MessageMiddleware
+func (m *Maintenance) Middleware(next MessageHandlerFunc) MessageHandlerFunc {
+	return func(msg *MessageContext) {
+		info := msg.Event.Info
+		if !m.InMaintenance() || info.IsFromMe || msg.Text == "" {
+			next(msg)
+			return
+		}
+		if m.resolver != nil && m.resolver.RoleOf(msg.Event, info.Sender) >= m.exemptRole {
+			next(msg)
+			return
+		}
+
+		// Tell each chat once instead of answering every message
+		m.mu.Lock()
+		first := !m.notified[info.Chat]
+		m.notified[info.Chat] = true
+		m.mu.Unlock()
+		if first {
+			msg.Reply(response.Text("🛠️ The bot is under maintenance. Please try again later."))
+		}
+	}
+}
+
+// MessageMetrics counts handled messages and how long handling took
+type MessageMetrics struct {
+	received  atomic.Int64
+	completed atomic.Int64
+	totalNs   atomic.Int64
+}
+
+// MessageMetricsSnapshot is a point-in-time copy of MessageMetrics
+type MessageMetricsSnapshot struct {
+	Received       int64         `json:"received"`
+	Completed      int64         `json:"completed"`       // Reached the end of the chain
+	Dropped        int64         `json:"dropped"`         // Stopped by a middleware
+	AverageLatency time.Duration `json:"average_latency"` // Of completed messages
+}
+
+// Middleware implements MessageMiddleware. Add it first so it sees every
+// message, including ones later middlewares drop.
+func (mm *MessageMetrics) Middleware(next MessageHandlerFunc) MessageHandlerFunc {
+	return func(msg *MessageContext) {
+		mm.received.Add(1)
+		start := time.Now()
+		next(msg)
+		if msg.completed {
+			mm.completed.Add(1)
+			mm.totalNs.Add(int64(time.Since(start)))
+		}
+	}
+}
+
+// Snapshot returns the current counters
+func (mm *MessageMetrics) Snapshot() MessageMetricsSnapshot {
+	s := MessageMetricsSnapshot{Received: mm.received.Load(), Completed: mm.completed.Load()}
+	s.Dropped = s.Received - s.Completed
+	if s.Completed > 0 {
+		s.AverageLatency = time.Duration(mm.totalNs.Load() / s.Completed)
+	}
+	return s
+}
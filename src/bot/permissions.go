@@ -0,0 +1,137 @@
+package bot
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+
+	"whatsappBotGo/src/bot/response"
+	"whatsappBotGo/src/bot/roles"
+)
+
+// RoleRequirer is implemented by commands that need more than roles.RoleUser
+type RoleRequirer interface {
+	RequiredRole() roles.Role
+}
+
+// GroupAdminFunc reports whether sender is an admin of the group chat
+type GroupAdminFunc func(chat, sender types.JID) bool
+
+// Permissions resolves the role of message senders from the roster and
+// WhatsApp group metadata
+type Permissions struct {
+	roster       *roles.Store
+	isGroupAdmin GroupAdminFunc
+}
+
+// NewPermissions creates a permission resolver; isGroupAdmin may be nil
+func NewPermissions(roster *roles.Store, isGroupAdmin GroupAdminFunc) *Permissions {
+	return &Permissions{roster: roster, isGroupAdmin: isGroupAdmin}
+}
+
+// Roster returns the underlying roster store
+func (p *Permissions) Roster() *roles.Store {
+	return p.roster
+}
+
+// RoleOf returns the effective role of sender for the given message
+func (p *Permissions) RoleOf(evt *events.Message, sender types.JID) roles.Role {
+	if p == nil || p.roster == nil {
+		return roles.RoleUser
+	}
+
+	ids := []types.JID{sender}
+	if evt != nil {
+		ids = append(ids, evt.Info.SenderAlt)
+	}
+	role := p.roster.RoleOf(ids...)
+
+	if role < roles.RoleGroupAdmin && evt != nil && evt.Info.IsGroup && p.isGroupAdmin != nil {
+		if p.isGroupAdmin(evt.Info.Chat, sender) {
+			role = roles.RoleGroupAdmin
+		}
+	}
+	return role
+}
+
+// PermissionMiddleware answers commands the sender is not allowed to run
+// instead of running them
+func (ch *CommandHandler) PermissionMiddleware(next MessageHandlerFunc) MessageHandlerFunc {
+	return func(msg *MessageContext) {
+		if req := msg.Command(); req != nil && req.Command != nil && !ch.canRun(req.Command, msg.Event, msg.Event.Info.Sender) {
+			msg.Reply(response.Text("⛔ You are not allowed to use this command."))
+			return
+		}
+		next(msg)
+	}
+}
+
+// groupAdminCache caches group admin lists so permission checks don't hit
+// the WhatsApp servers on every command
+type groupAdminCache struct {
+	client *whatsmeow.Client
+	ttl    time.Duration
+
+	mu     sync.Mutex
+	groups map[types.JID]groupAdmins
+}
+
+// groupInfoRetry is how long a failed group info request is cached
+const groupInfoRetry = 30 * time.Second
+
+type groupAdmins struct {
+	admins  map[types.JID]bool // nil after a failed request
+	expires time.Time
+}
+
+func newGroupAdminCache(client *whatsmeow.Client, ttl time.Duration) *groupAdminCache {
+	return &groupAdminCache{client: client, ttl: ttl, groups: make(map[types.JID]groupAdmins)}
+}
+
+// IsAdmin implements GroupAdminFunc
+func (c *groupAdminCache) IsAdmin(chat, sender types.JID) bool {
+	c.mu.Lock()
+	entry, ok := c.groups[chat]
+	c.mu.Unlock()
+
+	if !ok || time.Now().After(entry.expires) {
+		info, err := c.client.GetGroupInfo(context.Background(), chat)
+		if err != nil {
+			// Remember the failure for a while so a group whose info can't
+			// be fetched doesn't cost a request on every message
+			log.Printf("Failed to get group info for %s, treating nobody as admin for %s: %v", chat, groupInfoRetry, err)
+			c.mu.Lock()
+			c.groups[chat] = groupAdmins{expires: time.Now().Add(groupInfoRetry)}
+			c.mu.Unlock()
+			return false
+		}
+
+		entry = groupAdmins{admins: make(map[types.JID]bool), expires: time.Now().Add(c.ttl)}
+		for _, p := range info.Participants {
+			if p.IsAdmin || p.IsSuperAdmin {
+				for _, id := range []types.JID{p.JID, p.PhoneNumber, p.LID} {
+					if !id.IsEmpty() {
+						entry.admins[id.ToNonAD()] = true
+					}
+				}
+			}
+		}
+
+		c.mu.Lock()
+		c.groups[chat] = entry
+		c.mu.Unlock()
+	}
+	return entry.admins[sender.ToNonAD()]
+}
+
+// Invalidate drops the cached admin list of a group, e.g. after a change
+func (c *groupAdminCache) Invalidate(chat types.JID) {
+	c.mu.Lock()
+	delete(c.groups, chat)
+	c.mu.Unlock()
+}
@@ -0,0 +1,179 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"whatsappBotGo/src/bot/ratelimit"
+	"whatsappBotGo/src/bot/response"
+	"whatsappBotGo/src/bot/roles"
+	"whatsappBotGo/src/functions"
+)
+
+// Cooldowner is implemented by commands with their own per-user cooldown
+// instead of the configured per-command rate
+type Cooldowner interface {
+	Cooldown() time.Duration
+}
+
+// RateLimitConfig configures the rate limiting middleware
+type RateLimitConfig struct {
+	Sender     ratelimit.Rate // Per sender across all chats
+	Chat       ratelimit.Rate // Per chat across all senders
+	Command    ratelimit.Rate // Per sender and command
+	ExemptRole roles.Role     // Senders with this role or higher are never limited
+}
+
+// RateLimitConfigFromEnv reads RATE_LIMIT_SENDER, RATE_LIMIT_CHAT and
+// RATE_LIMIT_COMMAND, each given as <count>/<duration> such as "10/1m"
+func RateLimitConfigFromEnv() RateLimitConfig {
+	rate := func(key, defaultValue string) ratelimit.Rate {
+		r, err := ratelimit.ParseRate(functions.GetEnv(key, defaultValue))
+		if err != nil {
+			log.Printf("Invalid %s, using %s: %v", key, defaultValue, err)
+			r, _ = ratelimit.ParseRate(defaultValue)
+		}
+		return r
+	}
+	return RateLimitConfig{
+		Sender:     rate("RATE_LIMIT_SENDER", "10/1m"),
+		Chat:       rate("RATE_LIMIT_CHAT", "30/1m"),
+		Command:    rate("RATE_LIMIT_COMMAND", "5/1m"),
+		ExemptRole: roles.RoleAdmin,
+	}
+}
+
+// RateLimiter is a message middleware that limits how often senders, chats
+// and individual commands are handled
+type RateLimiter struct {
+	config   RateLimitConfig
+	resolver roles.Resolver
+	sender   *ratelimit.Limiter
+	chat     *ratelimit.Limiter
+
+	mu       sync.Mutex
+	commands map[string]*ratelimit.Limiter // command name or "autoreply" -> per-sender limiter
+	notified map[string]time.Time          // sender -> end of the window they were told about
+}
+
+// NewRateLimiter creates a rate limiter; resolver is used to exempt admins
+func NewRateLimiter(config RateLimitConfig, resolver roles.Resolver) *RateLimiter {
+	return &RateLimiter{
+		config:   config,
+		resolver: resolver,
+		sender:   ratelimit.NewLimiter(config.Sender),
+		chat:     ratelimit.NewLimiter(config.Chat),
+		commands: make(map[string]*ratelimit.Limiter),
+		notified: make(map[string]time.Time),
+	}
+}
+
+// Middleware implements MessageMiddleware. Commands count against the
+// sender, chat and command limits and are answered with a notice when over
+// them. Auto replies only count against their own per-sender limit and are
+// dropped silently, so chatting never uses up the allowance for commands.
+// Everything else, including unknown commands, is never limited.
+func (rl *RateLimiter) Middleware(next MessageHandlerFunc) MessageHandlerFunc {
+	return func(msg *MessageContext) {
+		if !msg.expectsReply() {
+			next(msg)
+			return
+		}
+		req := msg.Command()
+		switch {
+		case req == nil:
+			if !rl.allowAutoReply(msg) {
+				return
+			}
+		case req.Command != nil:
+			if wait := rl.allowCommand(msg, req.Command); wait > 0 {
+				msg.Reply(rl.notify(msg.Event.Info.Sender.ToNonAD().String(), wait))
+				return
+			}
+		}
+		next(msg)
+	}
+}
+
+// allowCommand takes from the buckets of a command, or returns how long
+// the sender has to wait
+func (rl *RateLimiter) allowCommand(msg *MessageContext, cmd ResponseCommand) time.Duration {
+	sender := msg.Event.Info.Sender.ToNonAD().String()
+	chat := msg.Event.Info.Chat.ToNonAD().String()
+	command := rl.limiter(cmd.Name(), commandRate(cmd, rl.config.Command))
+
+	// Check every bucket before taking from any, so a rejected message
+	// doesn't use up the sender's allowance
+	wait := max(rl.sender.Delay(sender), rl.chat.Delay(chat), command.Delay(sender))
+	if wait > 0 && !rl.exempt(msg) {
+		return wait
+	}
+	rl.sender.Take(sender)
+	rl.chat.Take(chat)
+	command.Take(sender)
+	return 0
+}
+
+// allowAutoReply takes from the sender's auto reply bucket, which auto
+// replies, including video downloads, share
+func (rl *RateLimiter) allowAutoReply(msg *MessageContext) bool {
+	sender := msg.Event.Info.Sender.ToNonAD().String()
+	autoReply := rl.limiter("autoreply", rl.config.Command)
+	if autoReply.Delay(sender) > 0 && !rl.exempt(msg) {
+		return false
+	}
+	autoReply.Take(sender)
+	return true
+}
+
+// exempt reports whether the sender has a role that is never limited. It is
+// only checked once a sender is over a limit, since finding out whether
+// someone is a group admin may take a request to WhatsApp.
+func (rl *RateLimiter) exempt(msg *MessageContext) bool {
+	return rl.resolver != nil && rl.resolver.RoleOf(msg.Event, msg.Event.Info.Sender) >= rl.config.ExemptRole
+}
+
+// commandRate returns the cooldown of cmd if it has one, else the default
+func commandRate(cmd ResponseCommand, defaultRate ratelimit.Rate) ratelimit.Rate {
+	if c, ok := unwrapCommand(cmd).(Cooldowner); ok {
+		return ratelimit.Every(c.Cooldown())
+	}
+	return defaultRate
+}
+
+// limiter returns the per-sender limiter with the given name
+func (rl *RateLimiter) limiter(name string, rate ratelimit.Rate) *ratelimit.Limiter {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	l, ok := rl.commands[name]
+	if !ok {
+		l = ratelimit.NewLimiter(rate)
+		rl.commands[name] = l
+	}
+	return l
+}
+
+// notify returns a "slow down" reply unless the sender was already told
+// within the current window
+func (rl *RateLimiter) notify(sender string, wait time.Duration) *response.Response {
+	now := time.Now()
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if until, ok := rl.notified[sender]; ok && now.Before(until) {
+		return nil
+	}
+	rl.notified[sender] = now.Add(wait)
+
+	// Forget old notices so the map doesn't grow forever
+	for s, until := range rl.notified {
+		if now.After(until) {
+			delete(rl.notified, s)
+		}
+	}
+
+	wait = max(time.Second, wait.Round(time.Second))
+	return response.Text(fmt.Sprintf("⏳ Slow down! Try again in %s.", wait))
+}
@@ -0,0 +1,149 @@
+package ratelimit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rate allows Count events per Per duration, with bursts of up to Count
+type Rate struct {
+	Count int
+	Per   time.Duration
+}
+
+// ParseRate parses rates such as "10/1m" or "3/10s". An empty string or
+// "0" disables the limit.
+func ParseRate(s string) (Rate, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "0" {
+		return Rate{}, nil
+	}
+
+	count, per, ok := strings.Cut(s, "/")
+	if !ok {
+		return Rate{}, fmt.Errorf("invalid rate %q, expected <count>/<duration>", s)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(count))
+	if err != nil || n < 0 {
+		return Rate{}, fmt.Errorf("invalid count in rate %q", s)
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(per))
+	if err != nil || d <= 0 {
+		return Rate{}, fmt.Errorf("invalid duration in rate %q", s)
+	}
+	return Rate{Count: n, Per: d}, nil
+}
+
+// Every returns a rate of one event per d, i.e. a cooldown
+func Every(d time.Duration) Rate {
+	return Rate{Count: 1, Per: d}
+}
+
+// Enabled reports whether the rate limits anything
+func (r Rate) Enabled() bool {
+	return r.Count > 0 && r.Per > 0
+}
+
+func (r Rate) String() string {
+	return fmt.Sprintf("%d/%s", r.Count, r.Per)
+}
+
+// Limiter is a set of token buckets, one per key
+type Limiter struct {
+	rate Rate
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter creates a limiter where each key gets its own bucket
+func NewLimiter(rate Rate) *Limiter {
+	return &Limiter{rate: rate, buckets: make(map[string]*bucket), lastSweep: time.Now()}
+}
+
+// Rate returns the rate of the limiter
+func (l *Limiter) Rate() Rate {
+	return l.rate
+}
+
+// Delay returns how long key has to wait for a token; zero means a token is
+// available now. It does not consume a token.
+func (l *Limiter) Delay(key string) time.Duration {
+	if !l.rate.Enabled() {
+		return 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.refill(key, time.Now())
+	if b.tokens >= 1 {
+		return 0
+	}
+	perToken := l.rate.Per / time.Duration(l.rate.Count)
+	return time.Duration((1 - b.tokens) * float64(perToken))
+}
+
+// Take consumes a token for key, if one is available
+func (l *Limiter) Take(key string) {
+	if !l.rate.Enabled() {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if b := l.refill(key, time.Now()); b.tokens >= 1 {
+		b.tokens--
+	}
+}
+
+// Allow consumes a token for key and reports whether one was available,
+// otherwise how long to wait for the next one
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	if d := l.Delay(key); d > 0 {
+		return false, d
+	}
+	l.Take(key)
+	return true, 0
+}
+
+// refill tops up the bucket of key; callers must hold l.mu
+func (l *Limiter) refill(key string, now time.Time) *bucket {
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.rate.Count), last: now}
+		l.buckets[key] = b
+		return b
+	}
+
+	elapsed := now.Sub(b.last)
+	b.tokens = min(float64(l.rate.Count), b.tokens+elapsed.Seconds()*float64(l.rate.Count)/l.rate.Per.Seconds())
+	b.last = now
+	return b
+}
+
+// sweep drops buckets that have been idle long enough to be full again, so
+// the map doesn't grow with every sender ever seen; callers must hold l.mu
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.rate.Per {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= l.rate.Per {
+			delete(l.buckets, key)
+		}
+	}
+}
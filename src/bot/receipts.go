@@ -0,0 +1,44 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+
+	"whatsappBotGo/src/api"
+	"whatsappBotGo/src/receipts"
+	"whatsappBotGo/src/senders"
+)
+
+// trackSent starts tracking receipts of a message sent through the senders
+func (bot *WhatsAppBot) trackSent(sent senders.SentMessage) {
+	if err := bot.receipts.Sent(sent.Result.Recipient, sent.Result.MessageID, sent.Result.Timestamp); err != nil {
+		log.Printf("Failed to track sent message %s: %v", sent.Result.MessageID, err)
+	}
+}
+
+// handleReceipt records a receipt and broadcasts the status changes it caused
+func (bot *WhatsAppBot) handleReceipt(evt *events.Receipt) {
+	if (evt.Type == types.ReceiptTypeRead || evt.Type == types.ReceiptTypeReadSelf) && len(evt.MessageIDs) > 0 {
+		fmt.Printf("Message %s was read\n", evt.MessageIDs[0])
+	}
+
+	changes, err := bot.receipts.Handle(evt)
+	if err != nil {
+		log.Printf("Failed to record receipt: %v", err)
+	}
+	for _, r := range changes {
+		chat, _ := types.ParseJID(r.Chat)
+		if err := bot.history.SetStatus(chat, r.MessageID, r.MessageStatus); err != nil {
+			log.Printf("Failed to update status of message %s: %v", r.MessageID, err)
+		}
+		bot.publish(api.EventMessageStatus, r)
+	}
+}
+
+// Receipts returns the tracker of delivery and read receipts
+func (bot *WhatsAppBot) Receipts() *receipts.Tracker {
+	return bot.receipts
+}
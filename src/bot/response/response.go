@@ -0,0 +1,109 @@
+package response
+
+import (
+	"go.mau.fi/whatsmeow/types"
+)
+
+// Kind identifies the type of an outgoing message
+type Kind string
+
+const (
+	KindText     Kind = "text"
+	KindImage    Kind = "image"
+	KindVideo    Kind = "video"
+	KindDocument Kind = "document"
+)
+
+// Message is a single outgoing message of a response
+type Message struct {
+	Kind    Kind
+	Text    string // Body of a text message
+	Path    string // Local file path of a media attachment
+	Caption string // Caption for images and videos
+	Title   string // Title for documents
+}
+
+// Response describes everything the bot sends back for an incoming message.
+// Messages are sent in order; a nil or empty response sends nothing.
+type Response struct {
+	Messages []Message
+	Reaction string    // Emoji to react to the incoming message with
+	NoQuote  bool      // Send messages without quoting the incoming message
+	Chat     types.JID // Overrides the chat the messages are sent to
+}
+
+// Text creates a response with a single text message.
+// An empty string yields a nil response so nothing is sent.
+func Text(text string) *Response {
+	if text == "" {
+		return nil
+	}
+	return (&Response{}).AddText(text)
+}
+
+// Image creates a response with a single image
+func Image(path, caption string) *Response {
+	return (&Response{}).AddImage(path, caption)
+}
+
+// Video creates a response with a single video
+func Video(path, caption string) *Response {
+	return (&Response{}).AddVideo(path, caption)
+}
+
+// Document creates a response with a single document
+func Document(path, title string) *Response {
+	return (&Response{}).AddDocument(path, title)
+}
+
+// React creates a response that only reacts to the incoming message
+func React(emoji string) *Response {
+	return &Response{Reaction: emoji}
+}
+
+// AddText appends a text message
+func (r *Response) AddText(text string) *Response {
+	r.Messages = append(r.Messages, Message{Kind: KindText, Text: text})
+	return r
+}
+
+// AddImage appends an image attachment
+func (r *Response) AddImage(path, caption string) *Response {
+	r.Messages = append(r.Messages, Message{Kind: KindImage, Path: path, Caption: caption})
+	return r
+}
+
+// AddVideo appends a video attachment
+func (r *Response) AddVideo(path, caption string) *Response {
+	r.Messages = append(r.Messages, Message{Kind: KindVideo, Path: path, Caption: caption})
+	return r
+}
+
+// AddDocument appends a document attachment
+func (r *Response) AddDocument(path, title string) *Response {
+	r.Messages = append(r.Messages, Message{Kind: KindDocument, Path: path, Title: title})
+	return r
+}
+
+// WithReaction sets the emoji to react to the incoming message with
+func (r *Response) WithReaction(emoji string) *Response {
+	r.Reaction = emoji
+	return r
+}
+
+// WithoutQuote sends the messages without quoting the incoming message
+func (r *Response) WithoutQuote() *Response {
+	r.NoQuote = true
+	return r
+}
+
+// To sends the messages to chat instead of the chat the message came from
+func (r *Response) To(chat types.JID) *Response {
+	r.Chat = chat
+	return r
+}
+
+// IsEmpty reports whether the response has nothing to send
+func (r *Response) IsEmpty() bool {
+	return r == nil || (len(r.Messages) == 0 && r.Reaction == "")
+}
@@ -0,0 +1,195 @@
+package roles
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// Role is a permission level; higher roles include all lower ones
+type Role int
+
+const (
+	RoleUser Role = iota
+	// RoleGroupAdmin is granted to WhatsApp group admins within their group.
+	// It is derived from group metadata and never stored in the roster.
+	RoleGroupAdmin
+	RoleModerator
+	RoleAdmin
+	// RoleOwner is configured through OWNER_JID and cannot be granted from chat
+	RoleOwner
+)
+
+var roleNames = map[Role]string{
+	RoleUser:       "user",
+	RoleGroupAdmin: "groupadmin",
+	RoleModerator:  "moderator",
+	RoleAdmin:      "admin",
+	RoleOwner:      "owner",
+}
+
+func (r Role) String() string {
+	if name, ok := roleNames[r]; ok {
+		return name
+	}
+	return fmt.Sprintf("role(%d)", int(r))
+}
+
+// ParseRole parses a role name that can be stored in the roster
+func ParseRole(s string) (Role, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "mod", "moderator":
+		return RoleModerator, nil
+	case "admin":
+		return RoleAdmin, nil
+	}
+	return RoleUser, fmt.Errorf("unknown role %q (use admin or moderator)", s)
+}
+
+// Resolver returns the effective role of a message sender
+type Resolver interface {
+	RoleOf(evt *events.Message, sender types.JID) Role
+}
+
+// Member is a roster entry
+type Member struct {
+	JID     types.JID
+	Role    Role
+	AddedBy types.JID
+	AddedAt time.Time
+}
+
+// Store persists the admin/moderator roster in SQLite
+type Store struct {
+	db    *sql.DB
+	owner types.JID
+
+	mu    sync.RWMutex
+	cache map[string]Role
+}
+
+// NewStore creates the roster table if needed and loads it into memory
+func NewStore(db *sql.DB, owner types.JID) (*Store, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS bot_roles (
+		jid      TEXT PRIMARY KEY,
+		role     TEXT NOT NULL,
+		added_by TEXT NOT NULL DEFAULT '',
+		added_at INTEGER NOT NULL
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create roles table: %w", err)
+	}
+
+	s := &Store{db: db, owner: owner.ToNonAD(), cache: make(map[string]Role)}
+	members, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range members {
+		s.cache[m.JID.String()] = m.Role
+	}
+	return s, nil
+}
+
+// Owner returns the configured owner JID
+func (s *Store) Owner() types.JID {
+	return s.owner
+}
+
+// RoleOf returns the highest role held by any of the given identities.
+// Pass both the phone number and LID of a sender when they are known.
+func (s *Store) RoleOf(ids ...types.JID) Role {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	best := RoleUser
+	for _, id := range ids {
+		if id.IsEmpty() {
+			continue
+		}
+		id = id.ToNonAD()
+		if !s.owner.IsEmpty() && id == s.owner {
+			return RoleOwner
+		}
+		if r, ok := s.cache[id.String()]; ok && r > best {
+			best = r
+		}
+	}
+	return best
+}
+
+// Set grants role to jid, replacing any previous role
+func (s *Store) Set(jid types.JID, role Role, addedBy types.JID) error {
+	if role != RoleModerator && role != RoleAdmin {
+		return fmt.Errorf("role %s cannot be stored in the roster", role)
+	}
+	jid = jid.ToNonAD()
+	_, err := s.db.Exec(`INSERT INTO bot_roles (jid, role, added_by, added_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (jid) DO UPDATE SET role=excluded.role, added_by=excluded.added_by, added_at=excluded.added_at`,
+		jid.String(), role.String(), addedBy.ToNonAD().String(), time.Now().Unix())
+	if err != nil {
+		return fmt.Errorf("failed to save role: %w", err)
+	}
+
+	s.mu.Lock()
+	s.cache[jid.String()] = role
+	s.mu.Unlock()
+	return nil
+}
+
+// Remove deletes jid from the roster and reports whether it was present
+func (s *Store) Remove(jid types.JID) (bool, error) {
+	jid = jid.ToNonAD()
+	res, err := s.db.Exec(`DELETE FROM bot_roles WHERE jid=?`, jid.String())
+	if err != nil {
+		return false, fmt.Errorf("failed to remove role: %w", err)
+	}
+
+	s.mu.Lock()
+	delete(s.cache, jid.String())
+	s.mu.Unlock()
+
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// List returns all roster members, highest role first
+func (s *Store) List() ([]Member, error) {
+	rows, err := s.db.Query(`SELECT jid, role, added_by, added_at FROM bot_roles`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list roles: %w", err)
+	}
+	defer rows.Close()
+
+	var members []Member
+	for rows.Next() {
+		var jid, role, addedBy string
+		var addedAt int64
+		if err := rows.Scan(&jid, &role, &addedBy, &addedAt); err != nil {
+			return nil, fmt.Errorf("failed to read role: %w", err)
+		}
+		m := Member{AddedAt: time.Unix(addedAt, 0)}
+		if m.JID, err = types.ParseJID(jid); err != nil {
+			continue
+		}
+		if m.Role, err = ParseRole(role); err != nil {
+			continue
+		}
+		m.AddedBy, _ = types.ParseJID(addedBy)
+		members = append(members, m)
+	}
+
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].Role != members[j].Role {
+			return members[i].Role > members[j].Role
+		}
+		return members[i].JID.String() < members[j].JID.String()
+	})
+	return members, rows.Err()
+}
@@ -0,0 +1,91 @@
+package settings
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+// Keys of per-chat settings
+const (
+	// KeySuggestions toggles "did you mean" replies for unknown commands
+	KeySuggestions = "suggestions"
+)
+
+// Store persists per-chat settings in SQLite
+type Store struct {
+	db *sql.DB
+
+	mu    sync.RWMutex
+	cache map[string]string // chat + "|" + key -> value
+}
+
+// NewStore creates the settings table if needed and loads it into memory
+func NewStore(db *sql.DB) (*Store, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS bot_chat_settings (
+		chat  TEXT NOT NULL,
+		key   TEXT NOT NULL,
+		value TEXT NOT NULL,
+		PRIMARY KEY (chat, key)
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create chat settings table: %w", err)
+	}
+
+	rows, err := db.Query(`SELECT chat, key, value FROM bot_chat_settings`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chat settings: %w", err)
+	}
+	defer rows.Close()
+
+	s := &Store{db: db, cache: make(map[string]string)}
+	for rows.Next() {
+		var chat, key, value string
+		if err := rows.Scan(&chat, &key, &value); err != nil {
+			return nil, fmt.Errorf("failed to read chat setting: %w", err)
+		}
+		s.cache[chat+"|"+key] = value
+	}
+	return s, rows.Err()
+}
+
+// Get returns a chat setting and whether it was set
+func (s *Store) Get(chat types.JID, key string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	v, ok := s.cache[chat.ToNonAD().String()+"|"+key]
+	return v, ok
+}
+
+// Bool returns a boolean chat setting or defaultValue if it is unset
+func (s *Store) Bool(chat types.JID, key string, defaultValue bool) bool {
+	if v, ok := s.Get(chat, key); ok {
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return defaultValue
+}
+
+// Set stores a chat setting
+func (s *Store) Set(chat types.JID, key, value string) error {
+	chatID := chat.ToNonAD().String()
+	_, err := s.db.Exec(`INSERT INTO bot_chat_settings (chat, key, value) VALUES (?, ?, ?)
+		ON CONFLICT (chat, key) DO UPDATE SET value=excluded.value`, chatID, key, value)
+	if err != nil {
+		return fmt.Errorf("failed to save chat setting: %w", err)
+	}
+
+	s.mu.Lock()
+	s.cache[chatID+"|"+key] = value
+	s.mu.Unlock()
+	return nil
+}
+
+// SetBool stores a boolean chat setting
+func (s *Store) SetBool(chat types.JID, key string, value bool) error {
+	return s.Set(chat, key, strconv.FormatBool(value))
+}
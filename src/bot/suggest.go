@@ -0,0 +1,60 @@
+package bot
+
+import (
+	"fmt"
+
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+
+	"whatsappBotGo/src/bot/settings"
+	"whatsappBotGo/src/internal/utils"
+)
+
+// SuggestionsEnabled reports whether unknown commands get a "did you mean"
+// reply in the chat of evt. Chats can override the configured default.
+func (ch *CommandHandler) SuggestionsEnabled(evt *events.Message, sender types.JID) bool {
+	chat, isGroup := sender, false
+	if evt != nil {
+		chat, isGroup = evt.Info.Chat, evt.Info.IsGroup
+	}
+
+	enabled := ch.suggestDirect
+	if isGroup {
+		enabled = ch.suggestGroups
+	}
+	if ch.settings != nil {
+		enabled = ch.settings.Bool(chat, settings.KeySuggestions, enabled)
+	}
+	return enabled
+}
+
+// suggest returns a "did you mean" reply for an unknown command name, or ""
+// when suggestions are off for the chat or nothing is close enough
+func (ch *CommandHandler) suggest(name string, evt *events.Message, sender types.JID) string {
+	if !ch.SuggestionsEnabled(evt, sender) {
+		return "" //return nothing if command not found
+	}
+
+	// Allow one typo for short names and roughly one per three characters beyond
+	maxDistance := max(1, len([]rune(name))/3)
+	best, bestDistance := "", maxDistance+1
+
+	consider := func(candidate string, cmd ResponseCommand) {
+		if d := utils.EditDistance(name, candidate); d < bestDistance && ch.canRun(cmd, evt, sender) {
+			best, bestDistance = candidate, d
+		}
+	}
+	for n, cmd := range ch.commands {
+		consider(n, cmd)
+	}
+	for alias, target := range ch.aliases {
+		if cmd, ok := ch.commands[target]; ok {
+			consider(alias, cmd)
+		}
+	}
+
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf("🤔 Unknown command %s%s. Did you mean %s%s?", ch.Prefix(), name, ch.Prefix(), best)
+}
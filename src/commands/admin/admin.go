@@ -0,0 +1,141 @@
+package admin
+
+import (
+	"fmt"
+	"strings"
+
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+
+	"whatsappBotGo/src/bot/args"
+	"whatsappBotGo/src/bot/roles"
+	"whatsappBotGo/src/internal/utils"
+)
+
+// AdminCommand manages the admin/moderator roster from chat
+type AdminCommand struct {
+	roster   *roles.Store
+	resolver roles.Resolver
+}
+
+func NewAdminCommand(roster *roles.Store, resolver roles.Resolver) *AdminCommand {
+	return &AdminCommand{roster: roster, resolver: resolver}
+}
+
+func (a *AdminCommand) Name() string             { return "admin" }
+func (a *AdminCommand) Description() string      { return "Manage bot admins and moderators" }
+func (a *AdminCommand) Category() string         { return "admin" }
+func (a *AdminCommand) RequiredRole() roles.Role { return roles.RoleAdmin }
+func (a *AdminCommand) Usage() string {
+	return "admin add <number|@mention> [admin|moderator]\nadmin remove <number|@mention>\nadmin list"
+}
+func (a *AdminCommand) Examples() []string {
+	return []string{"admin add +94771234567 moderator", "admin remove @someone", "admin list"}
+}
+
+func (a *AdminCommand) Execute(args *args.Args, sender types.JID) string {
+	return a.ExecuteWithContext(args, nil, sender)
+}
+
+func (a *AdminCommand) ExecuteWithContext(args *args.Args, evt *events.Message, sender types.JID) string {
+	switch strings.ToLower(args.Arg(0)) {
+	case "add":
+		return a.add(args, evt, sender)
+	case "remove", "rm", "del":
+		return a.remove(args, evt, sender)
+	case "list", "ls", "":
+		return a.list()
+	}
+	return "🤔 Unknown subcommand. Usage:\n" + a.Usage()
+}
+
+func (a *AdminCommand) add(args *args.Args, evt *events.Message, sender types.JID) string {
+	target, err := targetJID(args, evt)
+	if err != nil {
+		return "❌ " + err.Error()
+	}
+
+	role := roles.RoleModerator
+	if args.Len() > 2 {
+		if role, err = roles.ParseRole(args.Arg(2)); err != nil {
+			return "❌ " + err.Error()
+		}
+	}
+
+	// Only roles below your own can be granted, so admins manage moderators
+	// and the owner manages admins
+	callerRole := a.resolver.RoleOf(evt, sender)
+	if role >= callerRole {
+		return fmt.Sprintf("⛔ Only someone above %s can grant that role.", role)
+	}
+	if current := a.roster.RoleOf(target); current >= callerRole {
+		return "⛔ You cannot change the role of that user."
+	}
+
+	if err := a.roster.Set(target, role, sender); err != nil {
+		return "❌ " + err.Error()
+	}
+	return fmt.Sprintf("✅ %s is now %s.", displayJID(target), role)
+}
+
+func (a *AdminCommand) remove(args *args.Args, evt *events.Message, sender types.JID) string {
+	target, err := targetJID(args, evt)
+	if err != nil {
+		return "❌ " + err.Error()
+	}
+
+	current := a.roster.RoleOf(target)
+	if current >= a.resolver.RoleOf(evt, sender) {
+		return "⛔ You cannot remove that user."
+	}
+
+	removed, err := a.roster.Remove(target)
+	if err != nil {
+		return "❌ " + err.Error()
+	}
+	if !removed {
+		return fmt.Sprintf("🤔 %s is not on the roster.", displayJID(target))
+	}
+	return fmt.Sprintf("✅ %s was removed from the roster.", displayJID(target))
+}
+
+func (a *AdminCommand) list() string {
+	members, err := a.roster.List()
+	if err != nil {
+		return "❌ " + err.Error()
+	}
+
+	var response strings.Builder
+	response.WriteString("👮 *Bot Roster*\n\n")
+	if owner := a.roster.Owner(); !owner.IsEmpty() {
+		response.WriteString(fmt.Sprintf("• %s - owner\n", displayJID(owner)))
+	} else {
+		response.WriteString("• No owner configured (set OWNER_JID)\n")
+	}
+	for _, m := range members {
+		response.WriteString(fmt.Sprintf("• %s - %s\n", displayJID(m.JID), m.Role))
+	}
+	return strings.TrimRight(response.String(), "\n")
+}
+
+// targetJID returns the user a subcommand applies to, preferring a mention
+// over a typed number
+func targetJID(args *args.Args, evt *events.Message) (types.JID, error) {
+	if evt != nil && evt.Message != nil {
+		mentioned := evt.Message.GetExtendedTextMessage().GetContextInfo().GetMentionedJID()
+		if len(mentioned) > 0 {
+			return utils.ParseUserJID(mentioned[0])
+		}
+	}
+	if args.Len() < 2 {
+		return types.JID{}, fmt.Errorf("missing user, mention them or give their number")
+	}
+	return utils.ParseUserJID(args.Arg(1))
+}
+
+func displayJID(jid types.JID) string {
+	if jid.Server == types.DefaultUserServer {
+		return "+" + jid.User
+	}
+	return jid.String()
+}
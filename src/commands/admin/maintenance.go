@@ -0,0 +1,53 @@
+package admin
+
+import (
+	"strings"
+
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+
+	"whatsappBotGo/src/bot/args"
+	"whatsappBotGo/src/bot/roles"
+)
+
+// MaintenanceSwitch turns maintenance mode on and off
+type MaintenanceSwitch interface {
+	InMaintenance() bool
+	SetMaintenance(enabled bool)
+}
+
+// MaintenanceCommand toggles maintenance mode, in which only admins are served
+type MaintenanceCommand struct {
+	sw MaintenanceSwitch
+}
+
+func NewMaintenanceCommand(sw MaintenanceSwitch) *MaintenanceCommand {
+	return &MaintenanceCommand{sw: sw}
+}
+
+func (m *MaintenanceCommand) Name() string             { return "maintenance" }
+func (m *MaintenanceCommand) Description() string      { return "Turn maintenance mode on or off" }
+func (m *MaintenanceCommand) Category() string         { return "admin" }
+func (m *MaintenanceCommand) Usage() string            { return "maintenance [on|off]" }
+func (m *MaintenanceCommand) RequiredRole() roles.Role { return roles.RoleAdmin }
+
+func (m *MaintenanceCommand) Execute(args *args.Args, sender types.JID) string {
+	switch strings.ToLower(args.Arg(0)) {
+	case "":
+		if m.sw.InMaintenance() {
+			return "🛠️ Maintenance mode is *on*."
+		}
+		return "🟢 Maintenance mode is *off*."
+	case "on", "enable", "true":
+		m.sw.SetMaintenance(true)
+		return "🛠️ Maintenance mode turned on. Only admins are served now."
+	case "off", "disable", "false":
+		m.sw.SetMaintenance(false)
+		return "🟢 Maintenance mode turned off."
+	}
+	return "🤔 Use on or off."
+}
+
+func (m *MaintenanceCommand) ExecuteWithContext(args *args.Args, evt *events.Message, sender types.JID) string {
+	return m.Execute(args, sender)
+}
@@ -2,18 +2,23 @@ package fun
 
 import (
 	"math/rand"
+	"time"
 
 	"go.mau.fi/whatsmeow/types"
 	"go.mau.fi/whatsmeow/types/events"
+
+	"whatsappBotGo/src/bot/args"
 )
 
 type JokeCommand struct{}
 
-func NewJokeCommand() *JokeCommand         { return &JokeCommand{} }
-func (j *JokeCommand) Name() string        { return "/joke" }
-func (j *JokeCommand) Description() string { return "Get a random joke" }
+func NewJokeCommand() *JokeCommand             { return &JokeCommand{} }
+func (j *JokeCommand) Name() string            { return "joke" }
+func (j *JokeCommand) Description() string     { return "Get a random joke" }
+func (j *JokeCommand) Category() string        { return "fun" }
+func (j *JokeCommand) Cooldown() time.Duration { return 10 * time.Second }
 
-func (j *JokeCommand) Execute(args []string, sender types.JID) string {
+func (j *JokeCommand) Execute(args *args.Args, sender types.JID) string {
 	jokes := []string{
 		"😄 Why did the scarecrow win an award? Because he was outstanding in his field!",
 		"😂 What do you call a fake noodle? An impasta!",
@@ -24,6 +29,6 @@ func (j *JokeCommand) Execute(args []string, sender types.JID) string {
 	return jokes[rand.Intn(len(jokes))]
 }
 
-func (j *JokeCommand) ExecuteWithContext(args []string, evt *events.Message, sender types.JID) string {
+func (j *JokeCommand) ExecuteWithContext(args *args.Args, evt *events.Message, sender types.JID) string {
 	return j.Execute(args, sender)
 }
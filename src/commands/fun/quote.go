@@ -2,18 +2,23 @@ package fun
 
 import (
 	"math/rand"
+	"time"
 
 	"go.mau.fi/whatsmeow/types"
 	"go.mau.fi/whatsmeow/types/events"
+
+	"whatsappBotGo/src/bot/args"
 )
 
 type QuoteCommand struct{}
 
-func NewQuoteCommand() *QuoteCommand        { return &QuoteCommand{} }
-func (q *QuoteCommand) Name() string        { return "/quote" }
-func (q *QuoteCommand) Description() string { return "Get an inspirational quote" }
+func NewQuoteCommand() *QuoteCommand            { return &QuoteCommand{} }
+func (q *QuoteCommand) Name() string            { return "quote" }
+func (q *QuoteCommand) Description() string     { return "Get an inspirational quote" }
+func (q *QuoteCommand) Category() string        { return "fun" }
+func (q *QuoteCommand) Cooldown() time.Duration { return 10 * time.Second }
 
-func (q *QuoteCommand) Execute(args []string, sender types.JID) string {
+func (q *QuoteCommand) Execute(args *args.Args, sender types.JID) string {
 	quotes := []string{
 		"✨ \"The best time to plant a tree was 20 years ago. The second best time is now.\" - Chinese Proverb",
 		"🌟 \"Your time is limited, don't waste it living someone else's life.\" - Steve Jobs",
@@ -24,6 +29,6 @@ func (q *QuoteCommand) Execute(args []string, sender types.JID) string {
 	return quotes[rand.Intn(len(quotes))]
 }
 
-func (q *QuoteCommand) ExecuteWithContext(args []string, evt *events.Message, sender types.JID) string {
+func (q *QuoteCommand) ExecuteWithContext(args *args.Args, evt *events.Message, sender types.JID) string {
 	return q.Execute(args, sender)
 }
@@ -1,22 +1,26 @@
 package system
 
 import (
-	"strings"
-
 	"go.mau.fi/whatsmeow/types"
 	"go.mau.fi/whatsmeow/types/events"
+
+	"whatsappBotGo/src/bot/args"
 )
 
 type EchoCommand struct{}
 
 func NewEchoCommand() *EchoCommand         { return &EchoCommand{} }
-func (e *EchoCommand) Name() string        { return "/echo" }
+func (e *EchoCommand) Name() string        { return "echo" }
 func (e *EchoCommand) Description() string { return "Echo your message" }
+func (e *EchoCommand) Category() string    { return "system" }
+func (e *EchoCommand) Usage() string       { return "echo <text>" }
+func (e *EchoCommand) Examples() []string  { return []string{"echo Hello World"} }
+func (e *EchoCommand) Aliases() []string   { return []string{"say"} }
 
-func (e *EchoCommand) Execute(args []string, sender types.JID) string {
-	return "📢 " + strings.Join(args, " ")
+func (e *EchoCommand) Execute(args *args.Args, sender types.JID) string {
+	return "📢 " + args.Raw
 }
 
-func (e *EchoCommand) ExecuteWithContext(args []string, evt *events.Message, sender types.JID) string {
+func (e *EchoCommand) ExecuteWithContext(args *args.Args, evt *events.Message, sender types.JID) string {
 	return e.Execute(args, sender)
 }
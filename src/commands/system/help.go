@@ -2,44 +2,140 @@ package system
 
 import (
 	"fmt"
+	"slices"
+	"sort"
 	"strings"
 
 	"go.mau.fi/whatsmeow/types"
 	"go.mau.fi/whatsmeow/types/events"
+
+	"whatsappBotGo/src/bot/args"
 )
 
-type HelpCommand struct{}
+// HelpEntry describes a command for the help output. Names, usage lines,
+// examples and aliases are given without the command prefix.
+type HelpEntry struct {
+	Name        string
+	Description string
+	Category    string
+	Usage       string
+	Examples    []string
+	Aliases     []string
+}
+
+// CommandCatalog lists the commands a sender is allowed to run
+type CommandCatalog interface {
+	HelpEntries(evt *events.Message, sender types.JID) []HelpEntry
+	// Prefix returns the primary command prefix, e.g. "/"
+	Prefix() string
+}
+
+// categoryOrder is the order categories are listed in; others follow alphabetically
+var categoryOrder = []string{"system", "fun", "admin"}
+
+type HelpCommand struct {
+	catalog CommandCatalog
+}
 
-func NewHelpCommand() *HelpCommand {
-	return &HelpCommand{}
+func NewHelpCommand(catalog CommandCatalog) *HelpCommand {
+	return &HelpCommand{catalog: catalog}
 }
 
-func (h *HelpCommand) Name() string        { return "/help" }
+func (h *HelpCommand) Name() string        { return "help" }
 func (h *HelpCommand) Description() string { return "Show this help message" }
+func (h *HelpCommand) Category() string    { return "system" }
+func (h *HelpCommand) Usage() string       { return "help [command]" }
+func (h *HelpCommand) Examples() []string  { return []string{"help", "help echo"} }
+func (h *HelpCommand) Aliases() []string   { return []string{"h", "commands"} }
 
-func (h *HelpCommand) Execute(args []string, sender types.JID) string {
-	var response strings.Builder
-	response.WriteString("🤖 *WhatsApp Bot Commands:*\n\n")
-
-	// Predefined commands help text
-	commands := map[string]string{
-		"/help":  "Show this help message",
-		"/ping":  "Check if bot is alive",
-		"/time":  "Get current time",
-		"/echo":  "Echo your message",
-		"/info":  "Get your chat info",
-		"/joke":  "Get a random joke",
-		"/quote": "Get an inspirational quote",
+func (h *HelpCommand) Execute(args *args.Args, sender types.JID) string {
+	return h.ExecuteWithContext(args, nil, sender)
+}
+
+func (h *HelpCommand) ExecuteWithContext(args *args.Args, evt *events.Message, sender types.JID) string {
+	entries := h.catalog.HelpEntries(evt, sender)
+	prefix := h.catalog.Prefix()
+	if args.Len() > 0 {
+		return commandHelp(entries, prefix, args.Arg(0))
+	}
+	return overview(entries, prefix)
+}
+
+// overview lists all commands grouped by category
+func overview(entries []HelpEntry, prefix string) string {
+	byCategory := make(map[string][]HelpEntry)
+	for _, e := range entries {
+		category := e.Category
+		if category == "" {
+			category = "other"
+		}
+		byCategory[category] = append(byCategory[category], e)
 	}
 
-	for cmd, desc := range commands {
-		response.WriteString(fmt.Sprintf("%s - %s\n", cmd, desc))
+	var response strings.Builder
+	response.WriteString("🤖 *WhatsApp Bot Commands:*\n")
+
+	for _, category := range sortedCategories(byCategory) {
+		cmds := byCategory[category]
+		sort.Slice(cmds, func(i, j int) bool { return cmds[i].Name < cmds[j].Name })
+
+		response.WriteString(fmt.Sprintf("\n*%s*\n", strings.ToUpper(category[:1])+category[1:]))
+		for _, e := range cmds {
+			response.WriteString(fmt.Sprintf("%s%s - %s\n", prefix, e.Name, e.Description))
+		}
 	}
 
-	response.WriteString("\nSend any message to interact with the bot!")
+	response.WriteString(fmt.Sprintf("\nType %shelp <command> for details, or send any message to interact with the bot!", prefix))
 	return response.String()
 }
 
-func (h *HelpCommand) ExecuteWithContext(args []string, evt *events.Message, sender types.JID) string {
-	return h.Execute(args, sender)
+// commandHelp describes a single command looked up by name or alias
+func commandHelp(entries []HelpEntry, prefix, name string) string {
+	name = strings.TrimPrefix(strings.ToLower(name), prefix)
+
+	for _, e := range entries {
+		if e.Name != name && !slices.Contains(e.Aliases, name) {
+			continue
+		}
+
+		var response strings.Builder
+		response.WriteString(fmt.Sprintf("📖 *%s%s*\n%s\n", prefix, e.Name, e.Description))
+		if e.Usage != "" {
+			response.WriteString("\n*Usage:* " + withPrefix(prefix, strings.Split(e.Usage, "\n"), "\n"))
+		}
+		if len(e.Aliases) > 0 {
+			response.WriteString("\n*Aliases:* " + withPrefix(prefix, e.Aliases, ", "))
+		}
+		if len(e.Examples) > 0 {
+			response.WriteString("\n*Examples:*\n• " + withPrefix(prefix, e.Examples, "\n• "))
+		}
+		return strings.TrimRight(response.String(), "\n")
+	}
+	return fmt.Sprintf("🤔 Unknown command %s%s. Type %shelp to see available commands.", prefix, name, prefix)
+}
+
+// withPrefix prefixes each item and joins them with sep
+func withPrefix(prefix string, items []string, sep string) string {
+	prefixed := make([]string, len(items))
+	for i, item := range items {
+		prefixed[i] = prefix + item
+	}
+	return strings.Join(prefixed, sep)
+}
+
+// sortedCategories returns the categories in display order
+func sortedCategories(byCategory map[string][]HelpEntry) []string {
+	var categories, rest []string
+	for _, c := range categoryOrder {
+		if _, ok := byCategory[c]; ok {
+			categories = append(categories, c)
+		}
+	}
+	for c := range byCategory {
+		if !slices.Contains(categoryOrder, c) {
+			rest = append(rest, c)
+		}
+	}
+	sort.Strings(rest)
+	return append(categories, rest...)
 }
@@ -6,18 +6,21 @@ import (
 
 	"go.mau.fi/whatsmeow/types"
 	"go.mau.fi/whatsmeow/types/events"
+
+	"whatsappBotGo/src/bot/args"
 )
 
 type InfoCommand struct{}
 
 func NewInfoCommand() *InfoCommand         { return &InfoCommand{} }
-func (i *InfoCommand) Name() string        { return "/info" }
+func (i *InfoCommand) Name() string        { return "info" }
 func (i *InfoCommand) Description() string { return "Get your chat info" }
+func (i *InfoCommand) Category() string    { return "system" }
 
-func (i *InfoCommand) Execute(args []string, sender types.JID) string {
+func (i *InfoCommand) Execute(args *args.Args, sender types.JID) string {
 	return fmt.Sprintf("👤 *Your Chat Info:*\n\nJID: %s\nUser: %s", sender, strings.Split(sender.String(), "@")[0])
 }
 
-func (i *InfoCommand) ExecuteWithContext(args []string, evt *events.Message, sender types.JID) string {
+func (i *InfoCommand) ExecuteWithContext(args *args.Args, evt *events.Message, sender types.JID) string {
 	return i.Execute(args, sender)
 }
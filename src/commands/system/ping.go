@@ -5,18 +5,18 @@ import (
 
 	"go.mau.fi/whatsmeow/types"
 	"go.mau.fi/whatsmeow/types/events"
+
+	"whatsappBotGo/src/bot/args"
+	"whatsappBotGo/src/bot/response"
 )
 
 type PingCommand struct{}
 
 func NewPingCommand() *PingCommand         { return &PingCommand{} }
-func (p *PingCommand) Name() string        { return "/ping" }
+func (p *PingCommand) Name() string        { return "ping" }
 func (p *PingCommand) Description() string { return "Check if bot is alive" }
+func (p *PingCommand) Category() string    { return "system" }
 
-func (p *PingCommand) Execute(args []string, sender types.JID) string {
-	return "PONG - " + time.Now().Format(time.RFC3339)
-}
-
-func (p *PingCommand) ExecuteWithContext(args []string, evt *events.Message, sender types.JID) string {
-	return p.Execute(args, sender)
+func (p *PingCommand) Respond(args *args.Args, evt *events.Message, sender types.JID) *response.Response {
+	return response.Text("PONG - " + time.Now().Format(time.RFC3339)).WithReaction("🏓")
 }
@@ -0,0 +1,79 @@
+package system
+
+import (
+	"strings"
+
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+
+	"whatsappBotGo/src/bot/args"
+	"whatsappBotGo/src/bot/roles"
+	"whatsappBotGo/src/bot/settings"
+)
+
+// SuggestionState reports whether "did you mean" replies are on for a chat
+type SuggestionState interface {
+	SuggestionsEnabled(evt *events.Message, sender types.JID) bool
+}
+
+// SuggestionsCommand toggles "did you mean" replies for unknown commands in a chat
+type SuggestionsCommand struct {
+	settings *settings.Store
+	state    SuggestionState
+	resolver roles.Resolver
+}
+
+func NewSuggestionsCommand(s *settings.Store, state SuggestionState, resolver roles.Resolver) *SuggestionsCommand {
+	return &SuggestionsCommand{settings: s, state: state, resolver: resolver}
+}
+
+func (c *SuggestionsCommand) Name() string { return "suggestions" }
+func (c *SuggestionsCommand) Description() string {
+	return "Turn \"did you mean\" replies for unknown commands on or off in this chat"
+}
+func (c *SuggestionsCommand) Category() string   { return "system" }
+func (c *SuggestionsCommand) Usage() string      { return "suggestions [on|off]" }
+func (c *SuggestionsCommand) Examples() []string { return []string{"suggestions off"} }
+
+// Allowed lets anyone change their own direct chat, but only group admins
+// and above change a group
+func (c *SuggestionsCommand) Allowed(evt *events.Message, sender types.JID) bool {
+	if evt == nil || !evt.Info.IsGroup {
+		return true
+	}
+	return c.resolver.RoleOf(evt, sender) >= roles.RoleGroupAdmin
+}
+
+func (c *SuggestionsCommand) Execute(args *args.Args, sender types.JID) string {
+	return c.ExecuteWithContext(args, nil, sender)
+}
+
+func (c *SuggestionsCommand) ExecuteWithContext(args *args.Args, evt *events.Message, sender types.JID) string {
+	chat := sender
+	if evt != nil {
+		chat = evt.Info.Chat
+	}
+
+	var enabled bool
+	switch strings.ToLower(args.Arg(0)) {
+	case "":
+		if c.state.SuggestionsEnabled(evt, sender) {
+			return "💡 Command suggestions are *on* in this chat."
+		}
+		return "💡 Command suggestions are *off* in this chat."
+	case "on", "enable", "true":
+		enabled = true
+	case "off", "disable", "false":
+		enabled = false
+	default:
+		return "🤔 Use on or off."
+	}
+
+	if err := c.settings.SetBool(chat, settings.KeySuggestions, enabled); err != nil {
+		return "❌ " + err.Error()
+	}
+	if enabled {
+		return "✅ Command suggestions turned on."
+	}
+	return "✅ Command suggestions turned off."
+}
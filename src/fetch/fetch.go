@@ -0,0 +1,346 @@
+package fetch
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"whatsappBotGo/src/functions"
+)
+
+// Kind is the media kind a download must contain
+type Kind string
+
+const (
+	KindImage    Kind = "image"
+	KindVideo    Kind = "video"
+	KindDocument Kind = "document" // Any content
+)
+
+// Reason tells why a download was rejected
+type Reason string
+
+const (
+	ReasonInvalidURL   Reason = "invalid_url"
+	ReasonScheme       Reason = "scheme_not_allowed"
+	ReasonBlocked      Reason = "blocked_address"
+	ReasonRedirects    Reason = "too_many_redirects"
+	ReasonStatus       Reason = "bad_status"
+	ReasonTooLarge     Reason = "too_large"
+	ReasonWrongType    Reason = "wrong_type"
+	ReasonTimeout      Reason = "timeout"
+	ReasonUnreachable  Reason = "unreachable"
+	ReasonStorageError Reason = "storage_error"
+)
+
+// Error is a rejected or failed download
+type Error struct {
+	Reason  Reason
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func newError(reason Reason, format string, args ...any) *Error {
+	return &Error{Reason: reason, Message: fmt.Sprintf(format, args...)}
+}
+
+// Config limits what can be downloaded
+type Config struct {
+	Schemes      []string
+	MaxSize      int64 // In bytes
+	Timeout      time.Duration
+	MaxRedirects int
+	// AllowPrivate allows loopback, private and link-local addresses, e.g.
+	// to fetch from a service on the same host during development
+	AllowPrivate bool
+}
+
+// ConfigFromEnv reads the API_FETCH_* environment variables
+func ConfigFromEnv() Config {
+	return Config{
+		Schemes:      splitList(functions.GetEnv("API_FETCH_SCHEMES", "https,http")),
+		MaxSize:      int64(functions.GetEnvInt("API_FETCH_MAX_MB", 64)) << 20,
+		Timeout:      time.Duration(functions.GetEnvInt("API_FETCH_TIMEOUT_SECONDS", 30)) * time.Second,
+		MaxRedirects: functions.GetEnvInt("API_FETCH_MAX_REDIRECTS", 5),
+		AllowPrivate: functions.GetEnvBool("API_FETCH_ALLOW_PRIVATE", false),
+	}
+}
+
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// blocked lists ranges that are not on the public internet, besides those
+// covered by the netip.Addr predicates
+var blocked = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // "This" network
+	netip.MustParsePrefix("100.64.0.0/10"),   // Carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // Documentation
+	netip.MustParsePrefix("198.18.0.0/15"),   // Benchmarking
+	netip.MustParsePrefix("198.51.100.0/24"), // Documentation
+	netip.MustParsePrefix("203.0.113.0/24"),  // Documentation
+	netip.MustParsePrefix("240.0.0.0/4"),     // Reserved, including broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),    // NAT64, maps onto IPv4
+	netip.MustParsePrefix("64:ff9b:1::/48"),  // Local-use NAT64
+	netip.MustParsePrefix("2001:db8::/32"),   // Documentation
+}
+
+// isPublic reports whether addr is a public unicast address
+func isPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range blocked {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// blockedError is returned by the dialer for a non-public address
+type blockedError struct {
+	addr netip.Addr
+}
+
+func (e *blockedError) Error() string {
+	return fmt.Sprintf("address %s is not public", e.addr)
+}
+
+// Fetcher downloads media from caller-supplied URLs without letting them
+// reach the bot's own network. Addresses are checked when connecting, after
+// DNS resolution, so neither redirects nor DNS rebinding get around it.
+type Fetcher struct {
+	cfg    Config
+	client *http.Client
+}
+
+// New creates a fetcher
+func New(cfg Config) *Fetcher {
+	f := &Fetcher{cfg: cfg}
+	dialer := &net.Dialer{Timeout: 10 * time.Second, Control: f.checkAddress}
+	transport := &http.Transport{
+		// A proxy would connect on our behalf and skip the address check
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: cfg.Timeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       90 * time.Second,
+	}
+	f.client = &http.Client{Transport: transport, Timeout: cfg.Timeout, CheckRedirect: f.checkRedirect}
+	return f
+}
+
+// checkAddress runs before every connection with the resolved address
+func (f *Fetcher) checkAddress(network, address string, _ syscall.RawConn) error {
+	if f.cfg.AllowPrivate {
+		return nil
+	}
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !isPublic(addrPort.Addr()) {
+		return &blockedError{addr: addrPort.Addr()}
+	}
+	return nil
+}
+
+func (f *Fetcher) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) > f.cfg.MaxRedirects {
+		return newError(ReasonRedirects, "more than %d redirects", f.cfg.MaxRedirects)
+	}
+	return f.checkURL(req.URL)
+}
+
+func (f *Fetcher) checkURL(u *url.URL) error {
+	if !slices.Contains(f.cfg.Schemes, u.Scheme) {
+		return newError(ReasonScheme, "url scheme %q is not allowed, use %s", u.Scheme, strings.Join(f.cfg.Schemes, " or "))
+	}
+	if u.Hostname() == "" {
+		return newError(ReasonInvalidURL, "url has no host")
+	}
+	return nil
+}
+
+// Result is a downloaded file
+type Result struct {
+	Path     string // Temp file; the caller removes it
+	Mimetype string // Sniffed from the content
+	Size     int64
+}
+
+// Fetch downloads rawURL into a temp file in dir. The response must be 200
+// OK, at most MaxSize bytes, and hold media of the given kind.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string, kind Kind, dir string) (*Result, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, newError(ReasonInvalidURL, "invalid url: %v", err)
+	}
+	if err := f.checkURL(u); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, newError(ReasonInvalidURL, "invalid url: %v", err)
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, f.requestError(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newError(ReasonStatus, "url returned %s", resp.Status)
+	}
+	if f.cfg.MaxSize > 0 && resp.ContentLength > f.cfg.MaxSize {
+		return nil, newError(ReasonTooLarge, "file is %d bytes, the limit is %d", resp.ContentLength, f.cfg.MaxSize)
+	}
+
+	// Sniff the start of the body before storing anything
+	head := make([]byte, 512)
+	n, err := io.ReadFull(resp.Body, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, f.requestError(err)
+	}
+	head = head[:n]
+	mimetype, err := CheckKind(kind, head, resp.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.CreateTemp(dir, "api_media_*")
+	if err != nil {
+		return nil, newError(ReasonStorageError, "failed to create temp file: %v", err)
+	}
+	result := &Result{Path: tmp.Name(), Mimetype: mimetype}
+	err = f.copy(fileWriter{tmp}, io.MultiReader(bytes.NewReader(head), resp.Body), &result.Size)
+	if cerr := tmp.Close(); err == nil && cerr != nil {
+		err = newError(ReasonStorageError, "failed to write temp file: %v", cerr)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+	return result, nil
+}
+
+// copy writes the body to w, failing once it exceeds MaxSize
+func (f *Fetcher) copy(w io.Writer, body io.Reader, size *int64) error {
+	limit := f.cfg.MaxSize
+	if limit > 0 {
+		body = io.LimitReader(body, limit+1)
+	}
+	n, err := io.Copy(w, body)
+	*size = n
+	if err != nil {
+		var we *writeError
+		if errors.As(err, &we) {
+			return newError(ReasonStorageError, "failed to write temp file: %v", we.err)
+		}
+		return f.requestError(err)
+	}
+	if limit > 0 && n > limit {
+		return newError(ReasonTooLarge, "file is larger than the limit of %d bytes", limit)
+	}
+	return nil
+}
+
+// writeError marks errors of the temp file, as opposed to the download
+type writeError struct {
+	err error
+}
+
+func (e *writeError) Error() string {
+	return e.err.Error()
+}
+
+type fileWriter struct {
+	f *os.File
+}
+
+func (w fileWriter) Write(p []byte) (int, error) {
+	n, err := w.f.Write(p)
+	if err != nil {
+		err = &writeError{err: err}
+	}
+	return n, err
+}
+
+// requestError turns a client error into a download error
+func (f *Fetcher) requestError(err error) error {
+	var fe *Error
+	if errors.As(err, &fe) {
+		return fe
+	}
+	var be *blockedError
+	if errors.As(err, &be) {
+		return newError(ReasonBlocked, "url points to a non-public address (%s)", be.addr)
+	}
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return newError(ReasonTimeout, "download timed out after %s", f.cfg.Timeout)
+	}
+	return newError(ReasonUnreachable, "download failed: %v", err)
+}
+
+// CheckKind verifies that content of the given kind was returned and
+// returns its mimetype. The content decides; the Content-Type header is
+// only used when sniffing is inconclusive.
+func CheckKind(kind Kind, head []byte, contentType string) (string, error) {
+	sniffed := Sniff(head)
+	if kind == KindDocument {
+		return sniffed, nil
+	}
+
+	mimetype := sniffed
+	if sniffed == "application/octet-stream" {
+		if declared, _, err := mime.ParseMediaType(contentType); err == nil {
+			mimetype = declared
+		}
+	}
+	if !strings.HasPrefix(mimetype, string(kind)+"/") {
+		return "", newError(ReasonWrongType, "url returned %s, not %s", mimetype, article(kind))
+	}
+	return mimetype, nil
+}
+
+func article(kind Kind) string {
+	if kind == KindImage {
+		return "an " + string(kind)
+	}
+	return "a " + string(kind)
+}
+
+// Sniff returns the mimetype of content from its first bytes, without
+// parameters such as the charset
+func Sniff(head []byte) string {
+	mimetype, _, _ := strings.Cut(http.DetectContentType(head), ";")
+	return mimetype
+}
@@ -19,17 +19,17 @@ func NewAdminHandler(s *senders.Senders) *AdminHandler {
 }
 
 // SendSystemMessage sends a system-level message
-func (a *AdminHandler) SendSystemMessage(to types.JID, msg string) error {
+func (a *AdminHandler) SendSystemMessage(to types.JID, msg string) (*senders.SendResult, error) {
 	if a.senders == nil || a.senders.Text == nil {
-		return fmt.Errorf("text sender not configured")
+		return nil, fmt.Errorf("text sender not configured")
 	}
 	return a.senders.Text.SendText(to, fmt.Sprintf("🔧 *SYSTEM*: %s", msg))
 }
 
 // SendErrorAlert sends an error alert message
-func (a *AdminHandler) SendErrorAlert(to types.JID, err string) error {
+func (a *AdminHandler) SendErrorAlert(to types.JID, err string) (*senders.SendResult, error) {
 	if a.senders == nil || a.senders.Text == nil {
-		return fmt.Errorf("text sender not configured")
+		return nil, fmt.Errorf("text sender not configured")
 	}
 	return a.senders.Text.SendText(to, fmt.Sprintf("⚠️ *ERROR*: %s", err))
 }
@@ -40,7 +40,7 @@ func (a *AdminHandler) BroadcastMessage(recipients []types.JID, msg string) erro
 		return fmt.Errorf("text sender not configured")
 	}
 	for _, to := range recipients {
-		if err := a.senders.Text.SendText(to, msg); err != nil {
+		if _, err := a.senders.Text.SendText(to, msg); err != nil {
 			return fmt.Errorf("failed to broadcast to %s: %v", to, err)
 		}
 	}
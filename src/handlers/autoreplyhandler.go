@@ -10,6 +10,7 @@ import (
 	"strings"
 	"time"
 
+	"whatsappBotGo/src/bot/ratelimit"
 	"whatsappBotGo/src/functions"
 	"whatsappBotGo/src/senders"
 
@@ -27,6 +28,10 @@ type Config struct {
 	MaxFileSize         string
 	CleanupAfterSend    bool
 	EnableVideoDownload bool
+	// Download limits: per user per window, and how many run at once
+	MaxDownloadsPerUser    int
+	RateLimitWindow        int
+	MaxConcurrentDownloads int
 }
 
 // AutoReplyHandler handles automatic replies and special message processing
@@ -35,6 +40,8 @@ type AutoReplyHandler struct {
 	youtubeRegex *regexp.Regexp
 	tiktokRegex  *regexp.Regexp
 	config       *Config
+	downloads    *ratelimit.Limiter
+	slots        chan struct{} // Semaphore bounding concurrent downloads
 }
 
 // NewAutoReplyHandler creates a new AutoReplyHandler instance
@@ -50,6 +57,10 @@ func NewAutoReplyHandler() *AutoReplyHandler {
 		MaxFileSize:         functions.GetEnv("MAX_FILE_SIZE", "50MB"),
 		CleanupAfterSend:    functions.GetEnvBool("CLEANUP_AFTER_SEND", true),
 		EnableVideoDownload: functions.GetEnvBool("ENABLE_VIDEO_DOWNLOAD", true),
+
+		MaxDownloadsPerUser:    functions.GetEnvInt("MAX_DOWNLOADS_PER_USER", 10),
+		RateLimitWindow:        functions.GetEnvInt("RATE_LIMIT_WINDOW", 3600),
+		MaxConcurrentDownloads: functions.GetEnvInt("MAX_CONCURRENT_DOWNLOADS", 2),
 	}
 
 	// Create temp directory if it doesn't exist
@@ -63,6 +74,11 @@ func NewAutoReplyHandler() *AutoReplyHandler {
 		youtubeRegex: youtubeRegex,
 		tiktokRegex:  tiktokRegex,
 		config:       config,
+		downloads: ratelimit.NewLimiter(ratelimit.Rate{
+			Count: config.MaxDownloadsPerUser,
+			Per:   time.Duration(config.RateLimitWindow) * time.Second,
+		}),
+		slots: make(chan struct{}, max(1, config.MaxConcurrentDownloads)),
 	}
 }
 
@@ -77,8 +93,18 @@ func (a *AutoReplyHandler) ProcessMessage(message string, sender types.JID) stri
 
 	// Check for video links first
 	if a.containsVideoLink(message) {
+		if ok, wait := a.downloads.Allow(sender.ToNonAD().String()); !ok {
+			return fmt.Sprintf("⏳ You've reached the download limit. Try again in %s.", wait.Round(time.Second))
+		}
+
 		// Start background download and send via the sender
-		go a.handleVideoDownload(message, sender)
+		go func() {
+			// Wait for a free download slot so a burst of links can't start
+			// an unbounded number of downloads
+			a.slots <- struct{}{}
+			defer func() { <-a.slots }()
+			a.handleVideoDownload(message, sender)
+		}()
 		return "🎥 Video link detected! I'm downloading and processing it for you. Please wait..."
 	}
 
@@ -187,7 +213,7 @@ func (a *AutoReplyHandler) handleVideoDownload(message string, sender types.JID)
 
 	// Send the downloaded video
 	if a.senders != nil && a.senders.Video != nil {
-		err = a.senders.Video.SendVideo(sender, videoPath, fmt.Sprintf("🎥 Downloaded from %s", platform))
+		_, err = a.senders.Video.SendVideo(sender, videoPath, fmt.Sprintf("🎥 Downloaded from %s", platform))
 	} else {
 		err = fmt.Errorf("video sender not configured")
 	}
@@ -280,11 +306,11 @@ func (a *AutoReplyHandler) createDummyVideo(platform string) (string, error) {
 }
 
 // sendText wrapper function for external code to use
-func (a *AutoReplyHandler) SendText(to types.JID, text string) error {
+func (a *AutoReplyHandler) SendText(to types.JID, text string) (*senders.SendResult, error) {
 	if a.senders != nil && a.senders.Text != nil {
 		return a.senders.Text.SendText(to, text)
 	}
-	return fmt.Errorf("text sender not configured")
+	return nil, fmt.Errorf("text sender not configured")
 }
 
 // (env helpers moved to src/functions/env.go)
@@ -0,0 +1,333 @@
+package history
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+
+	"whatsappBotGo/src/whats"
+)
+
+var (
+	// ErrInvalidCursor is returned for cursors that were not issued by List
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrNotFound is returned for messages that are not stored
+	ErrNotFound = errors.New("message not found")
+)
+
+// Status is the delivery state of a stored message
+type Status string
+
+const (
+	StatusReceived  Status = "received"  // Incoming message
+	StatusSent      Status = "sent"      // Outgoing message accepted by the server
+	StatusDelivered Status = "delivered" // Reached a recipient's device
+	StatusRead      Status = "read"      // Seen by a recipient
+	StatusPlayed    Status = "played"    // Voice note or video played by a recipient
+)
+
+// Message is a stored incoming or outgoing message
+type Message struct {
+	ID        string            `json:"id"`
+	Chat      string            `json:"chat"`
+	Sender    string            `json:"sender"`
+	FromMe    bool              `json:"from_me"`
+	Timestamp time.Time         `json:"timestamp"`
+	Type      whats.MessageType `json:"type"`
+	Text      string            `json:"text,omitempty"`
+	QuotedID  string            `json:"quoted_id,omitempty"`
+	MediaID   string            `json:"media_id,omitempty"`
+	Status    Status            `json:"status"`
+	EditedAt  *time.Time        `json:"edited_at,omitempty"` // Last edit; Text is the edited text
+	Deleted   bool              `json:"deleted,omitempty"`   // Deleted for everyone; Text is cleared
+
+	seq int64
+}
+
+// Query selects messages for List. All fields are optional.
+type Query struct {
+	Chat   types.JID // Only messages of this chat
+	Search string    // Full-text search words; each must match a word prefix
+	Cursor string    // NextCursor of the previous page
+	Limit  int       // Page size, capped at MaxLimit
+}
+
+// Page is one page of List results, newest message first
+type Page struct {
+	Messages   []Message `json:"messages"`
+	NextCursor string    `json:"next_cursor,omitempty"` // Empty on the last page
+}
+
+const (
+	DefaultLimit = 50
+	MaxLimit     = 200
+)
+
+// Store keeps a transcript of every chat in SQLite with an FTS5 index
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates the message and search tables if needed
+func NewStore(db *sql.DB) (*Store, error) {
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS bot_messages (
+			seq       INTEGER PRIMARY KEY AUTOINCREMENT,
+			id        TEXT NOT NULL,
+			chat      TEXT NOT NULL,
+			sender    TEXT NOT NULL,
+			from_me   INTEGER NOT NULL,
+			timestamp INTEGER NOT NULL,
+			type      TEXT NOT NULL,
+			text      TEXT NOT NULL DEFAULT '',
+			quoted_id TEXT NOT NULL DEFAULT '',
+			media_id  TEXT NOT NULL DEFAULT '',
+			status    TEXT NOT NULL,
+			edited_at INTEGER NOT NULL DEFAULT 0,
+			deleted   INTEGER NOT NULL DEFAULT 0,
+			UNIQUE (chat, id)
+		)`,
+		`CREATE INDEX IF NOT EXISTS bot_messages_chat_time ON bot_messages (chat, timestamp, seq)`,
+		`CREATE INDEX IF NOT EXISTS bot_messages_time ON bot_messages (timestamp, seq)`,
+		`CREATE VIRTUAL TABLE IF NOT EXISTS bot_messages_fts USING fts5(
+			text, content='bot_messages', content_rowid='seq'
+		)`,
+		// Keep the external-content index in sync with the table
+		`CREATE TRIGGER IF NOT EXISTS bot_messages_ai AFTER INSERT ON bot_messages BEGIN
+			INSERT INTO bot_messages_fts (rowid, text) VALUES (new.seq, new.text);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS bot_messages_ad AFTER DELETE ON bot_messages BEGIN
+			INSERT INTO bot_messages_fts (bot_messages_fts, rowid, text) VALUES ('delete', old.seq, old.text);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS bot_messages_au AFTER UPDATE OF text ON bot_messages BEGIN
+			INSERT INTO bot_messages_fts (bot_messages_fts, rowid, text) VALUES ('delete', old.seq, old.text);
+			INSERT INTO bot_messages_fts (rowid, text) VALUES (new.seq, new.text);
+		END`,
+	}
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			return nil, fmt.Errorf("failed to create message history tables: %w", err)
+		}
+	}
+	// Tables created before edits and deletions were applied lack these
+	for _, column := range []string{"edited_at", "deleted"} {
+		if err := addColumn(db, "bot_messages", column, "INTEGER NOT NULL DEFAULT 0"); err != nil {
+			return nil, fmt.Errorf("failed to upgrade message history table: %w", err)
+		}
+	}
+	return &Store{db: db}, nil
+}
+
+// addColumn adds a column to table unless it already has it
+func addColumn(db *sql.DB, table, column, definition string) error {
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, table, column).Scan(&n); err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+	_, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
+
+// Record stores a message. Recording a message that is already stored,
+// e.g. after a retry, keeps the first copy. Edits and deletions are applied
+// to the message they target instead of being stored themselves.
+func (s *Store) Record(msg *whats.IncomingMessage, status Status) error {
+	switch msg.Type {
+	case whats.TypeEdit:
+		return s.applyEdit(msg)
+	case whats.TypeDelete:
+		return s.applyDelete(msg)
+	}
+
+	var mediaID string
+	if msg.Media != nil {
+		mediaID = msg.Media.ID
+	}
+	_, err := s.db.Exec(`INSERT INTO bot_messages (id, chat, sender, from_me, timestamp, type, text, quoted_id, media_id, status)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT (chat, id) DO NOTHING`,
+		msg.ID, msg.Chat.ToNonAD().String(), msg.Sender.ToNonAD().String(), msg.IsFromMe, msg.Timestamp.UnixMilli(),
+		string(msg.Type), msg.Text, msg.QuotedID, mediaID, string(status))
+	if err != nil {
+		return fmt.Errorf("failed to record message: %w", err)
+	}
+	return nil
+}
+
+// applyEdit replaces the text of the edited message, which the update
+// trigger also replaces in the search index. Only the author can edit.
+func (s *Store) applyEdit(msg *whats.IncomingMessage) error {
+	_, err := s.db.Exec(`UPDATE bot_messages SET text=?, edited_at=? WHERE chat=? AND id=? AND sender=? AND deleted=0`,
+		msg.Text, msg.Timestamp.UnixMilli(), msg.Chat.ToNonAD().String(), msg.TargetID, msg.Sender.ToNonAD().String())
+	if err != nil {
+		return fmt.Errorf("failed to apply edit: %w", err)
+	}
+	return nil
+}
+
+// applyDelete marks the deleted message and clears its text, removing it
+// from the search index. In groups admins can delete messages of others,
+// so the sender isn't checked.
+func (s *Store) applyDelete(msg *whats.IncomingMessage) error {
+	_, err := s.db.Exec(`UPDATE bot_messages SET text='', deleted=1 WHERE chat=? AND id=?`,
+		msg.Chat.ToNonAD().String(), msg.TargetID)
+	if err != nil {
+		return fmt.Errorf("failed to apply deletion: %w", err)
+	}
+	return nil
+}
+
+// SetStatus updates the delivery status of a stored message
+func (s *Store) SetStatus(chat types.JID, id string, status Status) error {
+	_, err := s.db.Exec(`UPDATE bot_messages SET status=? WHERE chat=? AND id=?`, string(status), chat.ToNonAD().String(), id)
+	if err != nil {
+		return fmt.Errorf("failed to update message: %w", err)
+	}
+	return nil
+}
+
+// SetMediaID links a stored message to its downloaded attachment
+func (s *Store) SetMediaID(chat types.JID, id, mediaID string) error {
+	_, err := s.db.Exec(`UPDATE bot_messages SET media_id=? WHERE chat=? AND id=?`, mediaID, chat.ToNonAD().String(), id)
+	if err != nil {
+		return fmt.Errorf("failed to update message: %w", err)
+	}
+	return nil
+}
+
+// Get returns one stored message of chat
+func (s *Store) Get(chat types.JID, id string) (*Message, error) {
+	row := s.db.QueryRow(`SELECT `+columns+` FROM bot_messages m WHERE chat = ? AND id = ?`, chat.ToNonAD().String(), id)
+	m, err := scanMessage(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read message: %w", err)
+	}
+	return m, nil
+}
+
+// List returns a page of messages matching q, newest first
+func (s *Store) List(q Query) (*Page, error) {
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	limit = min(limit, MaxLimit)
+
+	var where []string
+	var args []any
+	if !q.Chat.IsEmpty() {
+		where = append(where, "m.chat = ?")
+		args = append(args, q.Chat.ToNonAD().String())
+	}
+	if match := matchExpr(q.Search); match != "" {
+		where = append(where, "m.seq IN (SELECT rowid FROM bot_messages_fts WHERE bot_messages_fts MATCH ?)")
+		args = append(args, match)
+	}
+	if q.Cursor != "" {
+		ts, seq, err := decodeCursor(q.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		where = append(where, "(m.timestamp < ? OR (m.timestamp = ? AND m.seq < ?))")
+		args = append(args, ts, ts, seq)
+	}
+
+	query := `SELECT ` + columns + ` FROM bot_messages m`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY m.timestamp DESC, m.seq DESC LIMIT ?"
+	args = append(args, limit+1)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list messages: %w", err)
+	}
+	defer rows.Close()
+
+	page := &Page{Messages: []Message{}}
+	for rows.Next() {
+		m, err := scanMessage(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read message: %w", err)
+		}
+		page.Messages = append(page.Messages, *m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list messages: %w", err)
+	}
+
+	// One extra row was fetched to tell whether another page follows
+	if len(page.Messages) > limit {
+		page.Messages = page.Messages[:limit]
+		last := page.Messages[limit-1]
+		page.NextCursor = encodeCursor(last.Timestamp.UnixMilli(), last.seq)
+	}
+	return page, nil
+}
+
+// columns are the columns of bot_messages m read by scanMessage
+const columns = `m.seq, m.id, m.chat, m.sender, m.from_me, m.timestamp, m.type, m.text, m.quoted_id, m.media_id, m.status,
+	m.edited_at, m.deleted`
+
+// scanMessage reads a row of columns
+func scanMessage(row interface{ Scan(...any) error }) (*Message, error) {
+	var m Message
+	var ts, editedAt int64
+	var msgType, status string
+	err := row.Scan(&m.seq, &m.ID, &m.Chat, &m.Sender, &m.FromMe, &ts, &msgType, &m.Text, &m.QuotedID, &m.MediaID, &status,
+		&editedAt, &m.Deleted)
+	if err != nil {
+		return nil, err
+	}
+	m.Timestamp = time.UnixMilli(ts)
+	m.Type, m.Status = whats.MessageType(msgType), Status(status)
+	if editedAt > 0 {
+		t := time.UnixMilli(editedAt)
+		m.EditedAt = &t
+	}
+	return &m, nil
+}
+
+// matchExpr turns free text into an FTS5 query where every word must match
+// as a prefix, quoting the words so FTS5 operators in user input are inert
+func matchExpr(search string) string {
+	var terms []string
+	for _, word := range strings.Fields(search) {
+		terms = append(terms, `"`+strings.ReplaceAll(word, `"`, `""`)+`"*`)
+	}
+	return strings.Join(terms, " ")
+}
+
+func encodeCursor(ts, seq int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(ts, 10) + ":" + strconv.FormatInt(seq, 10)))
+}
+
+func decodeCursor(cursor string) (ts, seq int64, err error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, 0, ErrInvalidCursor
+	}
+	tsPart, seqPart, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return 0, 0, ErrInvalidCursor
+	}
+	if ts, err = strconv.ParseInt(tsPart, 10, 64); err != nil {
+		return 0, 0, ErrInvalidCursor
+	}
+	if seq, err = strconv.ParseInt(seqPart, 10, 64); err != nil {
+		return 0, 0, ErrInvalidCursor
+	}
+	return ts, seq, nil
+}
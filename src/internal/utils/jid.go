@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+// ParseUserJID parses a full JID or a plain phone number such as
+// "+94 77 123 4567" or "@94771234567" into a user JID
+func ParseUserJID(s string) (types.JID, error) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "@") && !strings.HasPrefix(s, "@") {
+		jid, err := types.ParseJID(s)
+		if err != nil {
+			return types.JID{}, err
+		}
+		return jid.ToNonAD(), nil
+	}
+
+	number := strings.NewReplacer("+", "", "@", "", " ", "", "-", "").Replace(s)
+	if number == "" {
+		return types.JID{}, fmt.Errorf("empty phone number")
+	}
+	for _, r := range number {
+		if r < '0' || r > '9' {
+			return types.JID{}, fmt.Errorf("invalid phone number %q", s)
+		}
+	}
+	return types.NewJID(number, types.DefaultUserServer), nil
+}
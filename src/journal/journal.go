@@ -0,0 +1,223 @@
+package journal
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// pruneEvery is how many appends pass between removals of old entries
+const pruneEvery = 100
+
+// maxPending is how many appended entries can wait for the writer before
+// Append blocks
+const maxPending = 1024
+
+// Entry is an encoded event with what subscription filters need to select it
+type Entry struct {
+	Seq     uint64
+	Type    string
+	Chat    string // Empty for events outside a chat
+	IsGroup bool
+	Text    string
+	Data    []byte
+}
+
+// Store keeps the most recent events so clients can resume after a
+// disconnect. Sequence numbers increase by one per event and are never
+// reused. Entries are written to SQLite in the background, so a slow disk
+// doesn't hold up Append.
+type Store struct {
+	db      *sql.DB
+	size    int
+	mu      sync.Mutex
+	stored  *sync.Cond // Signalled when the writer is done with a batch
+	last    uint64
+	pending []Entry // Appended but not written yet, oldest first
+	appends int
+
+	wake chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewStore creates the journal table if needed. size is the number of
+// events kept.
+func NewStore(db *sql.DB, size int) (*Store, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS bot_events (
+		seq        INTEGER PRIMARY KEY,
+		type       TEXT NOT NULL,
+		chat       TEXT NOT NULL DEFAULT '',
+		is_group   INTEGER NOT NULL DEFAULT 0,
+		text       TEXT NOT NULL DEFAULT '',
+		data       BLOB NOT NULL,
+		created_at INTEGER NOT NULL
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create event journal: %w", err)
+	}
+
+	s := &Store{
+		db:   db,
+		size: max(size, 1),
+		wake: make(chan struct{}, 1),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	s.stored = sync.NewCond(&s.mu)
+	if err := db.QueryRow(`SELECT COALESCE(MAX(seq), 0) FROM bot_events`).Scan(&s.last); err != nil {
+		return nil, fmt.Errorf("failed to read event journal: %w", err)
+	}
+	go s.run()
+	return s, nil
+}
+
+// Append assigns the next sequence number to e, encodes it with encode and
+// queues it for the writer. The sequence number is used even if encoding or
+// storing fails, so it is never handed out twice. Append only blocks when
+// maxPending entries are still waiting to be written.
+func (s *Store) Append(e Entry, encode func(seq uint64) ([]byte, error)) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for len(s.pending) >= maxPending {
+		s.stored.Wait()
+	}
+
+	s.last++
+	e.Seq = s.last
+	data, err := encode(e.Seq)
+	if err != nil {
+		return e, fmt.Errorf("failed to encode event %d: %w", e.Seq, err)
+	}
+	e.Data = data
+
+	s.pending = append(s.pending, e)
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return e, nil
+}
+
+// run writes pending entries until Close is called
+func (s *Store) run() {
+	defer close(s.done)
+	for {
+		select {
+		case <-s.wake:
+			s.flush()
+		case <-s.stop:
+			s.flush()
+			return
+		}
+	}
+}
+
+// flush writes the pending entries in one transaction. Entries that fail
+// to be written are logged and dropped, like events evicted early.
+func (s *Store) flush() {
+	s.mu.Lock()
+	batch := s.pending
+	s.mu.Unlock()
+	if len(batch) == 0 {
+		return
+	}
+
+	if err := s.write(batch); err != nil {
+		log.Printf("Failed to store events %d to %d: %v", batch[0].Seq, batch[len(batch)-1].Seq, err)
+	}
+
+	s.mu.Lock()
+	s.pending = s.pending[len(batch):]
+	s.stored.Broadcast()
+	s.mu.Unlock()
+}
+
+// write stores a batch of entries and prunes old ones now and then
+func (s *Store) write(batch []Entry) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UnixMilli()
+	for _, e := range batch {
+		_, err := tx.Exec(`INSERT INTO bot_events (seq, type, chat, is_group, text, data, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			e.Seq, e.Type, e.Chat, e.IsGroup, e.Text, e.Data, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	last := batch[len(batch)-1].Seq
+	if s.appends += len(batch); s.appends >= pruneEvery {
+		s.appends = 0
+		if _, err := tx.Exec(`DELETE FROM bot_events WHERE seq <= ?`, int64(last)-int64(s.size)); err != nil {
+			return fmt.Errorf("failed to prune: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// wait blocks until every entry up to until was written or given up on
+func (s *Store) wait(until uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(s.pending) > 0 && s.pending[0].Seq <= until {
+		s.stored.Wait()
+	}
+}
+
+// Close writes the pending entries and stops the writer
+func (s *Store) Close() {
+	select {
+	case <-s.stop:
+	default:
+		close(s.stop)
+	}
+	<-s.done
+}
+
+// Last returns the sequence number of the newest event, 0 if there is none
+func (s *Store) Last() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.last
+}
+
+// Oldest returns the sequence number of the oldest kept event, 0 if the
+// journal is empty
+func (s *Store) Oldest() (uint64, error) {
+	s.wait(s.Last())
+	var oldest uint64
+	if err := s.db.QueryRow(`SELECT COALESCE(MIN(seq), 0) FROM bot_events`).Scan(&oldest); err != nil {
+		return 0, fmt.Errorf("failed to read event journal: %w", err)
+	}
+	return oldest, nil
+}
+
+// Range returns up to limit events with after < seq <= until, oldest first.
+// It waits for those events to be written.
+func (s *Store) Range(after, until uint64, limit int) ([]Entry, error) {
+	s.wait(until)
+	rows, err := s.db.Query(`SELECT seq, type, chat, is_group, text, data FROM bot_events
+		WHERE seq > ? AND seq <= ? ORDER BY seq LIMIT ?`, after, until, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read event journal: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []Entry
+	for rows.Next() {
+		var e Entry
+		if err := rows.Scan(&e.Seq, &e.Type, &e.Chat, &e.IsGroup, &e.Text, &e.Data); err != nil {
+			return nil, fmt.Errorf("failed to read event: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
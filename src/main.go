@@ -3,13 +3,24 @@ package main
 import (
 	"context"
 	"log"
+	"os"
 	"time"
 
 	"whatsappBotGo/src/api"
+	"whatsappBotGo/src/apikeys"
 	"whatsappBotGo/src/bot"
+	"whatsappBotGo/src/functions"
 )
 
 func main() {
+	// Manage API keys without connecting to WhatsApp
+	if len(os.Args) > 1 && os.Args[1] == "apikey" {
+		if err := apikeys.RunCLI(functions.GetEnv("BOT_DB_PATH", "bot.db"), os.Args[2:], os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	// Create new bot instance
 	whatsappBot, err := bot.NewBot()
 	if err != nil {
@@ -21,6 +32,12 @@ func main() {
 		srv := api.NewServer(whatsappBot.Senders(), whatsappBot.InstanceUserID())
 		// Attach to bot so it can broadcast incoming messages
 		whatsappBot.SetAPIServer(srv)
+		srv.SetMediaStore(whatsappBot.MediaStore())
+		srv.SetHistory(whatsappBot.History())
+		srv.SetReceipts(whatsappBot.Receipts())
+		srv.SetJournal(whatsappBot.Journal())
+		srv.SetWebhooks(whatsappBot.Webhooks())
+		srv.SetAPIKeys(whatsappBot.APIKeys())
 		srv.Start()
 		defer func() {
 			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -0,0 +1,267 @@
+package media
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"whatsappBotGo/src/functions"
+)
+
+var (
+	// ErrNotFound is returned for unknown or expired media IDs
+	ErrNotFound = errors.New("media not found")
+	// ErrTooLarge is returned for files above the configured size cap
+	ErrTooLarge = errors.New("media exceeds the maximum file size")
+)
+
+// Config controls where media is stored and how long it is kept
+type Config struct {
+	Dir          string
+	MaxFileSize  int64         // Per file, in bytes; 0 disables the cap
+	MaxTotalSize int64         // Whole store, in bytes; 0 disables the cap
+	Retention    time.Duration // 0 keeps files until the total size cap evicts them
+}
+
+// ConfigFromEnv reads MEDIA_DIR, MEDIA_MAX_FILE_MB, MEDIA_MAX_TOTAL_MB and
+// MEDIA_RETENTION_HOURS
+func ConfigFromEnv() Config {
+	return Config{
+		Dir:          functions.GetEnv("MEDIA_DIR", "./media"),
+		MaxFileSize:  int64(functions.GetEnvInt("MEDIA_MAX_FILE_MB", 64)) << 20,
+		MaxTotalSize: int64(functions.GetEnvInt("MEDIA_MAX_TOTAL_MB", 1024)) << 20,
+		Retention:    time.Duration(functions.GetEnvInt("MEDIA_RETENTION_HOURS", 168)) * time.Hour,
+	}
+}
+
+// Info describes a stored file. ID is the hex SHA-256 of its content.
+type Info struct {
+	ID        string    `json:"id"`
+	Mimetype  string    `json:"mimetype"`
+	FileName  string    `json:"file_name,omitempty"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+
+	path string
+}
+
+// Store keeps media files in a local directory, named after the hash of
+// their content, and indexes them in SQLite
+type Store struct {
+	db  *sql.DB
+	cfg Config
+
+	mu   sync.Mutex // Serializes writes and cleanup
+	stop chan struct{}
+}
+
+// NewStore creates the media directory and index table if needed
+func NewStore(db *sql.DB, cfg Config) (*Store, error) {
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create media directory: %w", err)
+	}
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS bot_media (
+		id         TEXT PRIMARY KEY,
+		path       TEXT NOT NULL,
+		mimetype   TEXT NOT NULL DEFAULT '',
+		file_name  TEXT NOT NULL DEFAULT '',
+		size       INTEGER NOT NULL,
+		created_at INTEGER NOT NULL
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create media table: %w", err)
+	}
+	return &Store{db: db, cfg: cfg, stop: make(chan struct{})}, nil
+}
+
+// MaxFileSize returns the per-file cap in bytes, 0 if unlimited
+func (s *Store) MaxFileSize() int64 {
+	return s.cfg.MaxFileSize
+}
+
+// Save stores data and returns its info. Saving the same content again
+// returns the existing file and refreshes its retention.
+func (s *Store) Save(data []byte, mimetype, fileName string) (*Info, error) {
+	if s.cfg.MaxFileSize > 0 && int64(len(data)) > s.cfg.MaxFileSize {
+		return nil, ErrTooLarge
+	}
+
+	sum := sha256.Sum256(data)
+	id := hex.EncodeToString(sum[:])
+	info := &Info{ID: id, Mimetype: mimetype, Size: int64(len(data)), CreatedAt: time.Now()}
+	if fileName != "" {
+		info.FileName = filepath.Base(fileName)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// The file is named after the content only, so saving it again under
+	// another name or mimetype reuses it. Files indexed under an older
+	// naming scheme keep their path.
+	rel := filepath.Join(id[:2], id)
+	err := s.db.QueryRow(`SELECT path FROM bot_media WHERE id=?`, id).Scan(&rel)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to look up media: %w", err)
+	}
+	info.path = rel
+
+	full := filepath.Join(s.cfg.Dir, rel)
+	if _, err := os.Stat(full); err != nil {
+		if err := writeFile(full, data); err != nil {
+			return nil, fmt.Errorf("failed to write media: %w", err)
+		}
+	}
+
+	_, err = s.db.Exec(`INSERT INTO bot_media (id, path, mimetype, file_name, size, created_at) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET path=excluded.path, mimetype=excluded.mimetype,
+			file_name=CASE WHEN excluded.file_name != '' THEN excluded.file_name ELSE bot_media.file_name END,
+			created_at=excluded.created_at`,
+		id, rel, mimetype, info.FileName, info.Size, info.CreatedAt.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to index media: %w", err)
+	}
+	return info, nil
+}
+
+// writeFile writes data through a temp file so readers never see a partial file
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp_*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Get returns the info of a stored file
+func (s *Store) Get(id string) (*Info, error) {
+	info := &Info{ID: id}
+	var createdAt int64
+	err := s.db.QueryRow(`SELECT path, mimetype, file_name, size, created_at FROM bot_media WHERE id=?`, id).
+		Scan(&info.path, &info.Mimetype, &info.FileName, &info.Size, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up media: %w", err)
+	}
+	info.CreatedAt = time.Unix(createdAt, 0)
+	return info, nil
+}
+
+// Open returns a stored file for reading; the caller closes it
+func (s *Store) Open(id string) (*os.File, *Info, error) {
+	info, err := s.Get(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	f, err := os.Open(filepath.Join(s.cfg.Dir, info.path))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open media: %w", err)
+	}
+	return f, info, nil
+}
+
+// Cleanup removes files past the retention period, then the oldest files
+// until the store fits the total size cap. It returns the number removed.
+func (s *Store) Cleanup() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rows, err := s.db.Query(`SELECT id, path, size, created_at FROM bot_media ORDER BY created_at DESC`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list media: %w", err)
+	}
+	type entry struct {
+		id, path  string
+		size      int64
+		createdAt int64
+	}
+	var entries []entry
+	for rows.Next() {
+		var e entry
+		if err := rows.Scan(&e.id, &e.path, &e.size, &e.createdAt); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to read media: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	rows.Close()
+
+	var cutoff int64
+	if s.cfg.Retention > 0 {
+		cutoff = time.Now().Add(-s.cfg.Retention).Unix()
+	}
+
+	// Walk newest first so the oldest files go once the size cap is reached
+	removed := 0
+	var total int64
+	for _, e := range entries {
+		total += e.size
+		expired := cutoff > 0 && e.createdAt < cutoff
+		overCap := s.cfg.MaxTotalSize > 0 && total > s.cfg.MaxTotalSize
+		if !expired && !overCap {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.cfg.Dir, e.path)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("Failed to remove media %s: %v", e.id, err)
+			continue
+		}
+		if _, err := s.db.Exec(`DELETE FROM bot_media WHERE id=?`, e.id); err != nil {
+			return removed, fmt.Errorf("failed to remove media: %w", err)
+		}
+		total -= e.size
+		removed++
+	}
+	return removed, nil
+}
+
+// StartCleanup runs Cleanup every interval until Close is called
+func (s *Store) StartCleanup(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if n, err := s.Cleanup(); err != nil {
+				log.Printf("Media cleanup failed: %v", err)
+			} else if n > 0 {
+				fmt.Printf("Removed %d expired media files\n", n)
+			}
+			select {
+			case <-ticker.C:
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops the cleanup loop
+func (s *Store) Close() {
+	select {
+	case <-s.stop:
+	default:
+		close(s.stop)
+	}
+}
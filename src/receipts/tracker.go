@@ -0,0 +1,193 @@
+package receipts
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+
+	"whatsappBotGo/src/history"
+)
+
+// ErrNotFound is returned for messages that were not sent by the bot
+var ErrNotFound = errors.New("message not tracked")
+
+// ranks orders delivery states; a receipt never moves a message backwards,
+// e.g. a late delivery receipt after a read receipt
+var ranks = map[history.Status]int{
+	history.StatusSent:      1,
+	history.StatusDelivered: 2,
+	history.StatusRead:      3,
+	history.StatusPlayed:    4,
+}
+
+// Receipt is a status change of one recipient of an outgoing message
+type Receipt struct {
+	MessageID   string         `json:"message_id"`
+	Chat        string         `json:"chat"`
+	Participant string         `json:"participant"`
+	Status      history.Status `json:"status"`
+	Timestamp   time.Time      `json:"timestamp"`
+	// MessageStatus is the furthest state any recipient has reached
+	MessageStatus history.Status `json:"message_status"`
+}
+
+// ParticipantStatus is the state of an outgoing message for one recipient
+type ParticipantStatus struct {
+	JID       string         `json:"jid"`
+	Status    history.Status `json:"status"`
+	Timestamp time.Time      `json:"timestamp"`
+}
+
+// MessageStatus is the delivery state of an outgoing message. In groups,
+// Participants lists every member a receipt arrived from.
+type MessageStatus struct {
+	MessageID    string              `json:"message_id"`
+	Chat         string              `json:"chat"`
+	Status       history.Status      `json:"status"`
+	SentAt       time.Time           `json:"sent_at"`
+	UpdatedAt    time.Time           `json:"updated_at"`
+	Participants []ParticipantStatus `json:"participants"`
+}
+
+// Tracker records delivery, read and played receipts of messages sent by the bot
+type Tracker struct {
+	db *sql.DB
+}
+
+// NewTracker creates the receipt tables if needed
+func NewTracker(db *sql.DB) (*Tracker, error) {
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS bot_sent_messages (
+			id         TEXT PRIMARY KEY,
+			chat       TEXT NOT NULL,
+			status     TEXT NOT NULL,
+			rank       INTEGER NOT NULL,
+			sent_at    INTEGER NOT NULL,
+			updated_at INTEGER NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS bot_receipts (
+			message_id  TEXT NOT NULL REFERENCES bot_sent_messages (id) ON DELETE CASCADE,
+			participant TEXT NOT NULL,
+			status      TEXT NOT NULL,
+			rank        INTEGER NOT NULL,
+			timestamp   INTEGER NOT NULL,
+			PRIMARY KEY (message_id, participant)
+		)`,
+	}
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			return nil, fmt.Errorf("failed to create receipt tables: %w", err)
+		}
+	}
+	return &Tracker{db: db}, nil
+}
+
+// Sent starts tracking a message the bot sent
+func (t *Tracker) Sent(chat types.JID, id string, ts time.Time) error {
+	_, err := t.db.Exec(`INSERT INTO bot_sent_messages (id, chat, status, rank, sent_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO NOTHING`,
+		id, chat.ToNonAD().String(), string(history.StatusSent), ranks[history.StatusSent], ts.UnixMilli(), ts.UnixMilli())
+	if err != nil {
+		return fmt.Errorf("failed to track message: %w", err)
+	}
+	return nil
+}
+
+// statusOf maps a receipt type to the status it signals
+func statusOf(t types.ReceiptType) (history.Status, bool) {
+	switch t {
+	case types.ReceiptTypeDelivered:
+		return history.StatusDelivered, true
+	case types.ReceiptTypeRead:
+		return history.StatusRead, true
+	case types.ReceiptTypePlayed:
+		return history.StatusPlayed, true
+	}
+	return "", false
+}
+
+// Handle applies a receipt event and returns the changes it caused.
+// Receipts for messages the bot didn't send, receipts from this account's
+// own devices and receipts that would move a status backwards are ignored.
+func (t *Tracker) Handle(evt *events.Receipt) ([]Receipt, error) {
+	status, ok := statusOf(evt.Type)
+	if !ok || evt.IsFromMe {
+		return nil, nil
+	}
+	participant := evt.Sender.ToNonAD()
+	if participant.IsEmpty() {
+		participant = evt.Chat.ToNonAD()
+	}
+	rank := ranks[status]
+
+	var changes []Receipt
+	for _, id := range evt.MessageIDs {
+		res, err := t.db.Exec(`INSERT INTO bot_receipts (message_id, participant, status, rank, timestamp)
+			SELECT id, ?, ?, ?, ? FROM bot_sent_messages WHERE id=?
+			ON CONFLICT (message_id, participant) DO UPDATE SET status=excluded.status, rank=excluded.rank, timestamp=excluded.timestamp
+			WHERE excluded.rank > bot_receipts.rank`,
+			participant.String(), string(status), rank, evt.Timestamp.UnixMilli(), id)
+		if err != nil {
+			return changes, fmt.Errorf("failed to record receipt: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			continue // Not our message, or nothing new
+		}
+
+		if _, err := t.db.Exec(`UPDATE bot_sent_messages SET status=?, rank=?, updated_at=? WHERE id=? AND rank < ?`,
+			string(status), rank, evt.Timestamp.UnixMilli(), id, rank); err != nil {
+			return changes, fmt.Errorf("failed to update message status: %w", err)
+		}
+
+		var chat, messageStatus string
+		if err := t.db.QueryRow(`SELECT chat, status FROM bot_sent_messages WHERE id=?`, id).Scan(&chat, &messageStatus); err != nil {
+			return changes, fmt.Errorf("failed to read message status: %w", err)
+		}
+		changes = append(changes, Receipt{
+			MessageID:     id,
+			Chat:          chat,
+			Participant:   participant.String(),
+			Status:        status,
+			Timestamp:     evt.Timestamp,
+			MessageStatus: history.Status(messageStatus),
+		})
+	}
+	return changes, nil
+}
+
+// Status returns the delivery state of a message sent by the bot
+func (t *Tracker) Status(id string) (*MessageStatus, error) {
+	ms := &MessageStatus{MessageID: id, Participants: []ParticipantStatus{}}
+	var status string
+	var sentAt, updatedAt int64
+	err := t.db.QueryRow(`SELECT chat, status, sent_at, updated_at FROM bot_sent_messages WHERE id=?`, id).
+		Scan(&ms.Chat, &status, &sentAt, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read message status: %w", err)
+	}
+	ms.Status = history.Status(status)
+	ms.SentAt, ms.UpdatedAt = time.UnixMilli(sentAt), time.UnixMilli(updatedAt)
+
+	rows, err := t.db.Query(`SELECT participant, status, timestamp FROM bot_receipts WHERE message_id=? ORDER BY participant`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read receipts: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p ParticipantStatus
+		var ts int64
+		if err := rows.Scan(&p.JID, &status, &ts); err != nil {
+			return nil, fmt.Errorf("failed to read receipt: %w", err)
+		}
+		p.Status, p.Timestamp = history.Status(status), time.UnixMilli(ts)
+		ms.Participants = append(ms.Participants, p)
+	}
+	return ms, rows.Err()
+}
@@ -0,0 +1,40 @@
+package senders
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// clientChatStateSender implements ChatStateSender using a whatsmeow client
+type clientChatStateSender struct {
+	client *whatsmeow.Client
+}
+
+func NewChatStateSender(client *whatsmeow.Client) ChatStateSender {
+	return &clientChatStateSender{client: client}
+}
+
+func (s *clientChatStateSender) MarkRead(chat, sender types.JID, messageIDs ...string) error {
+	if err := s.client.MarkRead(context.Background(), messageIDs, time.Now(), chat.ToNonAD(), sender.ToNonAD()); err != nil {
+		return fmt.Errorf("failed to mark messages as read: %w", err)
+	}
+	return nil
+}
+
+func (s *clientChatStateSender) SetTyping(chat types.JID, typing, recording bool) error {
+	state, media := types.ChatPresencePaused, types.ChatPresenceMediaText
+	if typing || recording {
+		state = types.ChatPresenceComposing
+	}
+	if recording {
+		media = types.ChatPresenceMediaAudio
+	}
+	if err := s.client.SendChatPresence(context.Background(), chat.ToNonAD(), state, media); err != nil {
+		return fmt.Errorf("failed to send chat presence: %w", err)
+	}
+	return nil
+}
@@ -9,43 +9,62 @@ import (
 	waE2E "go.mau.fi/whatsmeow/proto/waE2E"
 	"go.mau.fi/whatsmeow/types"
 	"google.golang.org/protobuf/proto"
+
+	"whatsappBotGo/src/thumbnail"
 )
 
 // clientDocumentSender implements DocumentSender using a whatsmeow client
 type clientDocumentSender struct {
 	client *whatsmeow.Client
+	hooks  *Hooks
+	thumbs *thumbnail.Generator // Nil sends no thumbnails
 }
 
-func NewDocumentSender(client *whatsmeow.Client) DocumentSender {
-	return &clientDocumentSender{client: client}
+func NewDocumentSender(client *whatsmeow.Client, hooks *Hooks, thumbs *thumbnail.Generator) DocumentSender {
+	return &clientDocumentSender{client: client, hooks: hooks, thumbs: thumbs}
 }
 
-func (s *clientDocumentSender) SendDocument(to types.JID, docPath, title string) error {
+func (s *clientDocumentSender) SendDocument(to types.JID, docPath, title string) (*SendResult, error) {
 	return s.SendDocumentWithQuote(to, docPath, title, nil)
 }
 
-func (s *clientDocumentSender) SendDocumentWithQuote(to types.JID, docPath, title string, quotedMsg *QuotedMessage) error {
+func (s *clientDocumentSender) SendDocumentWithQuote(to types.JID, docPath, title string, quotedMsg *QuotedMessage) (*SendResult, error) {
+	return s.SendDocumentWithOptions(to, docPath, title, MediaOptions{Quoted: quotedMsg})
+}
+
+func (s *clientDocumentSender) SendDocumentWithOptions(to types.JID, docPath, title string, opts MediaOptions) (*SendResult, error) {
 	data, err := os.ReadFile(docPath)
 	if err != nil {
-		return fmt.Errorf("failed to read document: %w", err)
+		return nil, fmt.Errorf("failed to read document: %w", err)
 	}
 	uploaded, err := s.client.Upload(context.Background(), data, "document")
 	if err != nil {
-		return fmt.Errorf("failed to upload document: %w", err)
+		return nil, fmt.Errorf("failed to upload document: %w", err)
 	}
 
+	fileName := opts.fileName(docPath)
+	if title == "" {
+		title = fileName
+	}
+	mimetype := opts.mimetype(data, fileName)
 	docMsg := &waE2E.DocumentMessage{
 		URL:           proto.String(uploaded.URL),
 		DirectPath:    proto.String(uploaded.DirectPath),
 		MediaKey:      uploaded.MediaKey,
-		Mimetype:      proto.String("application/octet-stream"),
+		Mimetype:      proto.String(mimetype),
 		FileEncSHA256: uploaded.FileEncSHA256,
 		FileSHA256:    uploaded.FileSHA256,
 		FileLength:    proto.Uint64(uploaded.FileLength),
 		Title:         proto.String(title),
+		FileName:      proto.String(fileName),
+	}
+	if thumb := s.thumbs.Document(docPath, mimetype, data); thumb != nil {
+		docMsg.JPEGThumbnail = thumb.JPEG
+		docMsg.ThumbnailWidth = proto.Uint32(uint32(thumb.Width))
+		docMsg.ThumbnailHeight = proto.Uint32(uint32(thumb.Height))
 	}
 
-	if quotedMsg != nil {
+	if quotedMsg := opts.Quoted; quotedMsg != nil {
 		docMsg.ContextInfo = &waE2E.ContextInfo{
 			StanzaID:      proto.String(quotedMsg.MessageID),
 			Participant:   proto.String(quotedMsg.Sender.String()),
@@ -54,9 +73,9 @@ func (s *clientDocumentSender) SendDocumentWithQuote(to types.JID, docPath, titl
 	}
 
 	msg := &waE2E.Message{DocumentMessage: docMsg}
-	_, err = s.client.SendMessage(context.Background(), to.ToNonAD(), msg)
+	result, err := sendMessage(s.client, s.hooks, to, msg)
 	if err != nil {
-		return fmt.Errorf("failed to send document message: %w", err)
+		return nil, fmt.Errorf("failed to send document message: %w", err)
 	}
-	return nil
+	return result, nil
 }
@@ -1,13 +1,22 @@
 package senders
 
-import "go.mau.fi/whatsmeow"
+import (
+	"go.mau.fi/whatsmeow"
+
+	"whatsappBotGo/src/thumbnail"
+)
 
 // NewSendersFromClient creates Senders using a whatsmeow client
 func NewSendersFromClient(client *whatsmeow.Client) *Senders {
+	hooks := &Hooks{}
+	thumbs := thumbnail.New(thumbnail.ConfigFromEnv())
 	return &Senders{
-		Text:     NewTextSender(client),
-		Image:    NewImageSender(client),
-		Video:    NewVideoSender(client),
-		Document: NewDocumentSender(client),
+		Text:     NewTextSender(client, hooks),
+		Image:    NewImageSender(client, hooks, thumbs),
+		Video:    NewVideoSender(client, hooks, thumbs),
+		Document: NewDocumentSender(client, hooks, thumbs),
+		Reaction: NewReactionSender(client, hooks),
+		Chat:     NewChatStateSender(client),
+		Hooks:    hooks,
 	}
 }
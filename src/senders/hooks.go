@@ -0,0 +1,56 @@
+package senders
+
+import (
+	"context"
+	"sync"
+
+	"go.mau.fi/whatsmeow"
+	waE2E "go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// SentMessage describes a message that was delivered to the WhatsApp server
+type SentMessage struct {
+	Result   *SendResult
+	Message  *waE2E.Message
+	Response whatsmeow.SendResponse
+}
+
+// SentHook is called after every successful send
+type SentHook func(sent SentMessage)
+
+// Hooks lets other parts of the bot observe messages sent through Senders
+type Hooks struct {
+	mu   sync.RWMutex
+	sent []SentHook
+}
+
+// OnSent registers fn to be called after every successful send
+func (h *Hooks) OnSent(fn SentHook) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sent = append(h.sent, fn)
+}
+
+func (h *Hooks) notifySent(sent SentMessage) {
+	if h == nil {
+		return
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, fn := range h.sent {
+		fn(sent)
+	}
+}
+
+// sendMessage sends msg to the non-AD form of to and notifies hooks on success
+func sendMessage(client *whatsmeow.Client, hooks *Hooks, to types.JID, msg *waE2E.Message) (*SendResult, error) {
+	to = to.ToNonAD()
+	resp, err := client.SendMessage(context.Background(), to, msg)
+	if err != nil {
+		return nil, err
+	}
+	result := &SendResult{MessageID: resp.ID, Timestamp: resp.Timestamp, Recipient: to}
+	hooks.notifySent(SentMessage{Result: result, Message: msg, Response: resp})
+	return result, nil
+}
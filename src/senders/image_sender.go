@@ -9,43 +9,58 @@ import (
 	waE2E "go.mau.fi/whatsmeow/proto/waE2E"
 	"go.mau.fi/whatsmeow/types"
 	"google.golang.org/protobuf/proto"
+
+	"whatsappBotGo/src/thumbnail"
 )
 
 // clientImageSender implements ImageSender using a whatsmeow client
 type clientImageSender struct {
 	client *whatsmeow.Client
+	hooks  *Hooks
+	thumbs *thumbnail.Generator // Nil sends no thumbnails
 }
 
-func NewImageSender(client *whatsmeow.Client) ImageSender {
-	return &clientImageSender{client: client}
+func NewImageSender(client *whatsmeow.Client, hooks *Hooks, thumbs *thumbnail.Generator) ImageSender {
+	return &clientImageSender{client: client, hooks: hooks, thumbs: thumbs}
 }
 
-func (s *clientImageSender) SendImage(to types.JID, imagePath, caption string) error {
+func (s *clientImageSender) SendImage(to types.JID, imagePath, caption string) (*SendResult, error) {
 	return s.SendImageWithQuote(to, imagePath, caption, nil)
 }
 
-func (s *clientImageSender) SendImageWithQuote(to types.JID, imagePath, caption string, quotedMsg *QuotedMessage) error {
+func (s *clientImageSender) SendImageWithQuote(to types.JID, imagePath, caption string, quotedMsg *QuotedMessage) (*SendResult, error) {
+	return s.SendImageWithOptions(to, imagePath, caption, MediaOptions{Quoted: quotedMsg})
+}
+
+func (s *clientImageSender) SendImageWithOptions(to types.JID, imagePath, caption string, opts MediaOptions) (*SendResult, error) {
 	data, err := os.ReadFile(imagePath)
 	if err != nil {
-		return fmt.Errorf("failed to read image: %w", err)
+		return nil, fmt.Errorf("failed to read image: %w", err)
 	}
 	uploaded, err := s.client.Upload(context.Background(), data, "image")
 	if err != nil {
-		return fmt.Errorf("failed to upload image: %w", err)
+		return nil, fmt.Errorf("failed to upload image: %w", err)
 	}
 
 	imgMsg := &waE2E.ImageMessage{
 		URL:           proto.String(uploaded.URL),
 		DirectPath:    proto.String(uploaded.DirectPath),
 		MediaKey:      uploaded.MediaKey,
-		Mimetype:      proto.String("image/jpeg"),
+		Mimetype:      proto.String(opts.mimetype(data, opts.fileName(imagePath))),
 		FileEncSHA256: uploaded.FileEncSHA256,
 		FileSHA256:    uploaded.FileSHA256,
 		FileLength:    proto.Uint64(uploaded.FileLength),
 		Caption:       proto.String(caption),
 	}
+	if width, height := imageSize(data); width > 0 {
+		imgMsg.Width = proto.Uint32(width)
+		imgMsg.Height = proto.Uint32(height)
+	}
+	if thumb := s.thumbs.Image(data); thumb != nil {
+		imgMsg.JPEGThumbnail = thumb.JPEG
+	}
 
-	if quotedMsg != nil {
+	if quotedMsg := opts.Quoted; quotedMsg != nil {
 		imgMsg.ContextInfo = &waE2E.ContextInfo{
 			StanzaID:      proto.String(quotedMsg.MessageID),
 			Participant:   proto.String(quotedMsg.Sender.String()),
@@ -54,9 +69,9 @@ func (s *clientImageSender) SendImageWithQuote(to types.JID, imagePath, caption
 	}
 
 	msg := &waE2E.Message{ImageMessage: imgMsg}
-	_, err = s.client.SendMessage(context.Background(), to.ToNonAD(), msg)
+	result, err := sendMessage(s.client, s.hooks, to, msg)
 	if err != nil {
-		return fmt.Errorf("failed to send image message: %w", err)
+		return nil, fmt.Errorf("failed to send image message: %w", err)
 	}
-	return nil
+	return result, nil
 }
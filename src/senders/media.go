@@ -0,0 +1,132 @@
+package senders
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// MediaOptions adjusts how a media message is described to the recipient
+type MediaOptions struct {
+	Quoted *QuotedMessage
+	// Mimetype overrides the type sniffed from the content
+	Mimetype string
+	// FileName is shown for documents and used to guess types that can't be
+	// sniffed; it defaults to the base name of the path
+	FileName string
+}
+
+// fileName returns the name a file is sent under
+func (o MediaOptions) fileName(path string) string {
+	if o.FileName != "" {
+		return o.FileName
+	}
+	return filepath.Base(path)
+}
+
+// mimetype returns the override or the type detected from data and name
+func (o MediaOptions) mimetype(data []byte, name string) string {
+	if o.Mimetype != "" {
+		return o.Mimetype
+	}
+	return detectMimetype(data, name)
+}
+
+// genericTypes are sniffed for containers that hold more specific formats,
+// e.g. application/zip for DOCX and text/plain for CSV
+var genericTypes = map[string]bool{
+	"application/octet-stream": true,
+	"application/zip":          true,
+	"text/plain":               true,
+}
+
+// extensionTypes covers common documents that Go only knows when the
+// system has a mime.types file
+var extensionTypes = map[string]string{
+	".csv":  "text/csv",
+	".doc":  "application/msword",
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".xls":  "application/vnd.ms-excel",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".ppt":  "application/vnd.ms-powerpoint",
+	".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	".odt":  "application/vnd.oasis.opendocument.text",
+	".ods":  "application/vnd.oasis.opendocument.spreadsheet",
+	".epub": "application/epub+zip",
+	".apk":  "application/vnd.android.package-archive",
+	".mov":  "video/quicktime",
+	".md":   "text/markdown",
+}
+
+// detectMimetype sniffs the content of a file and falls back to its
+// extension when the content only tells the container
+func detectMimetype(data []byte, name string) string {
+	sniffed, _, _ := strings.Cut(http.DetectContentType(data), ";")
+	if sniffed == "application/octet-stream" && isQuickTime(data) {
+		return "video/quicktime"
+	}
+	if !genericTypes[sniffed] {
+		return sniffed
+	}
+	ext := strings.ToLower(filepath.Ext(name))
+	if byExt, ok := extensionTypes[ext]; ok {
+		return byExt
+	}
+	if byExt := mime.TypeByExtension(ext); byExt != "" {
+		byExt, _, _ = strings.Cut(byExt, ";")
+		return byExt
+	}
+	return sniffed
+}
+
+// isQuickTime reports whether data starts with the ftyp box of a MOV file,
+// which http.DetectContentType doesn't know
+func isQuickTime(data []byte) bool {
+	return len(data) >= 12 && string(data[4:8]) == "ftyp" && string(data[8:12]) == "qt  "
+}
+
+// imageSize returns the width and height of an image, or zeros when they
+// can't be read
+func imageSize(data []byte) (width, height uint32) {
+	if cfg, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+		return uint32(cfg.Width), uint32(cfg.Height)
+	}
+	return webpSize(data)
+}
+
+// webpSize reads the dimensions from the header of a WebP image, which the
+// standard library can't decode
+func webpSize(data []byte) (width, height uint32) {
+	if len(data) < 30 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return 0, 0
+	}
+	chunk := data[12:]
+	switch string(chunk[0:4]) {
+	case "VP8 ":
+		// Lossy: 14-bit sizes after the frame tag and start code
+		if chunk[11] != 0x9d || chunk[12] != 0x01 || chunk[13] != 0x2a {
+			return 0, 0
+		}
+		return uint32(binary.LittleEndian.Uint16(chunk[14:16]) & 0x3fff),
+			uint32(binary.LittleEndian.Uint16(chunk[16:18]) & 0x3fff)
+	case "VP8L":
+		// Lossless: two 14-bit sizes minus one after the signature byte
+		if chunk[8] != 0x2f {
+			return 0, 0
+		}
+		bits := binary.LittleEndian.Uint32(chunk[9:13])
+		return bits&0x3fff + 1, (bits>>14)&0x3fff + 1
+	case "VP8X":
+		// Extended: 24-bit canvas sizes minus one
+		return (uint32(chunk[12]) | uint32(chunk[13])<<8 | uint32(chunk[14])<<16) + 1,
+			(uint32(chunk[15]) | uint32(chunk[16])<<8 | uint32(chunk[17])<<16) + 1
+	}
+	return 0, 0
+}
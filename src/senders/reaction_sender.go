@@ -0,0 +1,27 @@
+package senders
+
+import (
+	"fmt"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// clientReactionSender implements ReactionSender using a whatsmeow client
+type clientReactionSender struct {
+	client *whatsmeow.Client
+	hooks  *Hooks
+}
+
+func NewReactionSender(client *whatsmeow.Client, hooks *Hooks) ReactionSender {
+	return &clientReactionSender{client: client, hooks: hooks}
+}
+
+func (s *clientReactionSender) SendReaction(chat, sender types.JID, messageID, emoji string) (*SendResult, error) {
+	msg := s.client.BuildReaction(chat.ToNonAD(), sender.ToNonAD(), messageID, emoji)
+	result, err := sendMessage(s.client, s.hooks, chat, msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send reaction: %w", err)
+	}
+	return result, nil
+}
@@ -1,6 +1,8 @@
 package senders
 
 import (
+	"time"
+
 	waE2E "go.mau.fi/whatsmeow/proto/waE2E"
 	"go.mau.fi/whatsmeow/types"
 )
@@ -12,28 +14,56 @@ type QuotedMessage struct {
 	Message   *waE2E.Message // The original message proto
 }
 
-// TextSender sends text messages
+// SendResult identifies a sent message so it can later be replied to,
+// edited, revoked or matched with its receipts
+type SendResult struct {
+	MessageID string    `json:"message_id"`
+	Timestamp time.Time `json:"timestamp"` // Server timestamp
+	Recipient types.JID `json:"recipient"`
+}
+
+// TextSender sends text messages and returns what was sent
 type TextSender interface {
-	SendText(to types.JID, text string) error
-	SendTextWithQuote(to types.JID, text string, quotedMsg *QuotedMessage) error
+	SendText(to types.JID, text string) (*SendResult, error)
+	SendTextWithQuote(to types.JID, text string, quotedMsg *QuotedMessage) (*SendResult, error)
 }
 
-// ImageSender sends image messages
+// ImageSender sends image messages and returns what was sent
 type ImageSender interface {
-	SendImage(to types.JID, imagePath, caption string) error
-	SendImageWithQuote(to types.JID, imagePath, caption string, quotedMsg *QuotedMessage) error
+	SendImage(to types.JID, imagePath, caption string) (*SendResult, error)
+	SendImageWithQuote(to types.JID, imagePath, caption string, quotedMsg *QuotedMessage) (*SendResult, error)
+	SendImageWithOptions(to types.JID, imagePath, caption string, opts MediaOptions) (*SendResult, error)
 }
 
-// VideoSender sends video messages
+// VideoSender sends video messages and returns what was sent
 type VideoSender interface {
-	SendVideo(to types.JID, videoPath, caption string) error
-	SendVideoWithQuote(to types.JID, videoPath, caption string, quotedMsg *QuotedMessage) error
+	SendVideo(to types.JID, videoPath, caption string) (*SendResult, error)
+	SendVideoWithQuote(to types.JID, videoPath, caption string, quotedMsg *QuotedMessage) (*SendResult, error)
+	SendVideoWithOptions(to types.JID, videoPath, caption string, opts MediaOptions) (*SendResult, error)
 }
 
-// DocumentSender sends document messages
+// DocumentSender sends document messages and returns what was sent
 type DocumentSender interface {
-	SendDocument(to types.JID, docPath, title string) error
-	SendDocumentWithQuote(to types.JID, docPath, title string, quotedMsg *QuotedMessage) error
+	SendDocument(to types.JID, docPath, title string) (*SendResult, error)
+	SendDocumentWithQuote(to types.JID, docPath, title string, quotedMsg *QuotedMessage) (*SendResult, error)
+	SendDocumentWithOptions(to types.JID, docPath, title string, opts MediaOptions) (*SendResult, error)
+}
+
+// ReactionSender reacts to messages with an emoji
+type ReactionSender interface {
+	// SendReaction reacts to the message with messageID sent by sender in chat.
+	// An empty emoji removes a previous reaction.
+	SendReaction(chat, sender types.JID, messageID, emoji string) (*SendResult, error)
+}
+
+// ChatStateSender marks messages as read and shows typing in a chat
+type ChatStateSender interface {
+	// MarkRead sends read receipts for messages of sender in chat; sender
+	// is only needed in groups
+	MarkRead(chat, sender types.JID, messageIDs ...string) error
+	// SetTyping shows or clears "typing..." or, with recording,
+	// "recording audio..."
+	SetTyping(chat types.JID, typing, recording bool) error
 }
 
 // Senders aggregates all sender interfaces
@@ -42,4 +72,7 @@ type Senders struct {
 	Image    ImageSender
 	Video    VideoSender
 	Document DocumentSender
+	Reaction ReactionSender
+	Chat     ChatStateSender
+	Hooks    *Hooks // Observes messages sent by any of the senders
 }
@@ -1,7 +1,6 @@
 package senders
 
 import (
-	"context"
 	"fmt"
 
 	"go.mau.fi/whatsmeow"
@@ -13,17 +12,18 @@ import (
 // clientTextSender implements TextSender using a whatsmeow client
 type clientTextSender struct {
 	client *whatsmeow.Client
+	hooks  *Hooks
 }
 
-func NewTextSender(client *whatsmeow.Client) TextSender {
-	return &clientTextSender{client: client}
+func NewTextSender(client *whatsmeow.Client, hooks *Hooks) TextSender {
+	return &clientTextSender{client: client, hooks: hooks}
 }
 
-func (s *clientTextSender) SendText(to types.JID, text string) error {
+func (s *clientTextSender) SendText(to types.JID, text string) (*SendResult, error) {
 	return s.SendTextWithQuote(to, text, nil)
 }
 
-func (s *clientTextSender) SendTextWithQuote(to types.JID, text string, quotedMsg *QuotedMessage) error {
+func (s *clientTextSender) SendTextWithQuote(to types.JID, text string, quotedMsg *QuotedMessage) (*SendResult, error) {
 	textMsg := &waE2E.ExtendedTextMessage{
 		Text: proto.String(text),
 	}
@@ -40,9 +40,9 @@ func (s *clientTextSender) SendTextWithQuote(to types.JID, text string, quotedMs
 		ExtendedTextMessage: textMsg,
 	}
 
-	_, err := s.client.SendMessage(context.Background(), to.ToNonAD(), msg)
+	result, err := sendMessage(s.client, s.hooks, to, msg)
 	if err != nil {
-		return fmt.Errorf("failed to send text message: %w", err)
+		return nil, fmt.Errorf("failed to send text message: %w", err)
 	}
-	return nil
+	return result, nil
 }
@@ -9,43 +9,56 @@ import (
 	waE2E "go.mau.fi/whatsmeow/proto/waE2E"
 	"go.mau.fi/whatsmeow/types"
 	"google.golang.org/protobuf/proto"
+
+	"whatsappBotGo/src/thumbnail"
 )
 
 // clientVideoSender implements VideoSender using a whatsmeow client
 type clientVideoSender struct {
 	client *whatsmeow.Client
+	hooks  *Hooks
+	thumbs *thumbnail.Generator // Nil sends no thumbnails
 }
 
-func NewVideoSender(client *whatsmeow.Client) VideoSender {
-	return &clientVideoSender{client: client}
+func NewVideoSender(client *whatsmeow.Client, hooks *Hooks, thumbs *thumbnail.Generator) VideoSender {
+	return &clientVideoSender{client: client, hooks: hooks, thumbs: thumbs}
 }
 
-func (s *clientVideoSender) SendVideo(to types.JID, videoPath, caption string) error {
+func (s *clientVideoSender) SendVideo(to types.JID, videoPath, caption string) (*SendResult, error) {
 	return s.SendVideoWithQuote(to, videoPath, caption, nil)
 }
 
-func (s *clientVideoSender) SendVideoWithQuote(to types.JID, videoPath, caption string, quotedMsg *QuotedMessage) error {
+func (s *clientVideoSender) SendVideoWithQuote(to types.JID, videoPath, caption string, quotedMsg *QuotedMessage) (*SendResult, error) {
+	return s.SendVideoWithOptions(to, videoPath, caption, MediaOptions{Quoted: quotedMsg})
+}
+
+func (s *clientVideoSender) SendVideoWithOptions(to types.JID, videoPath, caption string, opts MediaOptions) (*SendResult, error) {
 	data, err := os.ReadFile(videoPath)
 	if err != nil {
-		return fmt.Errorf("failed to read video: %w", err)
+		return nil, fmt.Errorf("failed to read video: %w", err)
 	}
 	uploaded, err := s.client.Upload(context.Background(), data, "video")
 	if err != nil {
-		return fmt.Errorf("failed to upload video: %w", err)
+		return nil, fmt.Errorf("failed to upload video: %w", err)
 	}
 
 	videoMsg := &waE2E.VideoMessage{
 		URL:           proto.String(uploaded.URL),
 		DirectPath:    proto.String(uploaded.DirectPath),
 		MediaKey:      uploaded.MediaKey,
-		Mimetype:      proto.String("video/mp4"),
+		Mimetype:      proto.String(opts.mimetype(data, opts.fileName(videoPath))),
 		FileEncSHA256: uploaded.FileEncSHA256,
 		FileSHA256:    uploaded.FileSHA256,
 		FileLength:    proto.Uint64(uploaded.FileLength),
 		Caption:       proto.String(caption),
 	}
+	if thumb := s.thumbs.Video(videoPath); thumb != nil {
+		videoMsg.JPEGThumbnail = thumb.JPEG
+		videoMsg.Width = proto.Uint32(uint32(thumb.SourceWidth))
+		videoMsg.Height = proto.Uint32(uint32(thumb.SourceHeight))
+	}
 
-	if quotedMsg != nil {
+	if quotedMsg := opts.Quoted; quotedMsg != nil {
 		videoMsg.ContextInfo = &waE2E.ContextInfo{
 			StanzaID:      proto.String(quotedMsg.MessageID),
 			Participant:   proto.String(quotedMsg.Sender.String()),
@@ -54,9 +67,9 @@ func (s *clientVideoSender) SendVideoWithQuote(to types.JID, videoPath, caption
 	}
 
 	msg := &waE2E.Message{VideoMessage: videoMsg}
-	_, err = s.client.SendMessage(context.Background(), to.ToNonAD(), msg)
+	result, err := sendMessage(s.client, s.hooks, to, msg)
 	if err != nil {
-		return fmt.Errorf("failed to send video message: %w", err)
+		return nil, fmt.Errorf("failed to send video message: %w", err)
 	}
-	return nil
+	return result, nil
 }
@@ -0,0 +1,58 @@
+package thumbnail
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"os/exec"
+	"strings"
+)
+
+// Extractor renders a still image of a file Go can't decode itself, such
+// as a frame of a video or the first page of a PDF
+type Extractor interface {
+	Extract(ctx context.Context, path string) (image.Image, error)
+}
+
+// FFmpeg extracts a representative frame of a video with ffmpeg
+type FFmpeg struct {
+	Path string // The ffmpeg binary
+}
+
+// Extract picks a frame among the first ones that isn't e.g. a black fade-in
+func (f FFmpeg) Extract(ctx context.Context, path string) (image.Image, error) {
+	return run(ctx, f.Path, "-v", "error", "-i", path,
+		"-vf", "thumbnail", "-frames:v", "1",
+		"-f", "image2pipe", "-c:v", "mjpeg", "-q:v", "3", "-")
+}
+
+// Pdftoppm renders the first page of a PDF with pdftoppm from poppler
+type Pdftoppm struct {
+	Path string // The pdftoppm binary
+}
+
+// Extract renders the first page, already scaled down
+func (p Pdftoppm) Extract(ctx context.Context, path string) (image.Image, error) {
+	// Without an output root pdftoppm writes the page to stdout
+	return run(ctx, p.Path, "-jpeg", "-f", "1", "-l", "1", "-scale-to", "256", path)
+}
+
+// run runs an extractor command and decodes the image it writes to stdout
+func run(ctx context.Context, name string, args ...string) (image.Image, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s failed: %w: %s", name, err, msg)
+		}
+		return nil, fmt.Errorf("%s failed: %w", name, err)
+	}
+	img, err := decode(stdout.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("%s output: %w", name, err)
+	}
+	return img, nil
+}
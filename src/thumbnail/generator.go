@@ -0,0 +1,116 @@
+package thumbnail
+
+import (
+	"context"
+	"log"
+	"os/exec"
+	"strings"
+	"time"
+
+	"whatsappBotGo/src/functions"
+)
+
+// Config selects which thumbnails are made
+type Config struct {
+	Enabled      bool
+	FFmpegPath   string // Video frames are skipped if it isn't installed
+	PDF          bool   // Render the first page of PDF documents
+	PdftoppmPath string
+	Timeout      time.Duration // For each ffmpeg or pdftoppm run
+}
+
+// ConfigFromEnv reads THUMBNAILS, THUMBNAIL_PDF, FFMPEG_PATH, PDFTOPPM_PATH
+// and THUMBNAIL_TIMEOUT_SECONDS
+func ConfigFromEnv() Config {
+	return Config{
+		Enabled:      functions.GetEnvBool("THUMBNAILS", true),
+		FFmpegPath:   functions.GetEnv("FFMPEG_PATH", "ffmpeg"),
+		PDF:          functions.GetEnvBool("THUMBNAIL_PDF", false),
+		PdftoppmPath: functions.GetEnv("PDFTOPPM_PATH", "pdftoppm"),
+		Timeout:      time.Duration(functions.GetEnvInt("THUMBNAIL_TIMEOUT_SECONDS", 10)) * time.Second,
+	}
+}
+
+// Generator makes the thumbnails of outgoing media. Images are scaled in
+// Go; videos and PDFs need an Extractor, and are sent without a thumbnail
+// when theirs is nil or fails. A nil Generator makes no thumbnails.
+type Generator struct {
+	VideoFrames Extractor
+	PDFPages    Extractor
+	Timeout     time.Duration
+}
+
+// New creates a generator with the extractors that are installed, nil if
+// thumbnails are disabled
+func New(cfg Config) *Generator {
+	if !cfg.Enabled {
+		return nil
+	}
+	g := &Generator{Timeout: cfg.Timeout}
+	if path, err := exec.LookPath(cfg.FFmpegPath); err == nil {
+		g.VideoFrames = FFmpeg{Path: path}
+	} else {
+		log.Printf("%s not found, videos are sent without thumbnails", cfg.FFmpegPath)
+	}
+	if cfg.PDF {
+		if path, err := exec.LookPath(cfg.PdftoppmPath); err == nil {
+			g.PDFPages = Pdftoppm{Path: path}
+		} else {
+			log.Printf("%s not found, PDFs are sent without thumbnails", cfg.PdftoppmPath)
+		}
+	}
+	return g
+}
+
+// Image makes the thumbnail of an image, nil for formats Go can't decode
+// such as WebP
+func (g *Generator) Image(data []byte) *Thumbnail {
+	if g == nil {
+		return nil
+	}
+	thumb, err := FromData(data)
+	if err != nil {
+		return nil
+	}
+	return thumb
+}
+
+// Video makes the thumbnail of a video from one of its frames
+func (g *Generator) Video(path string) *Thumbnail {
+	if g == nil {
+		return nil
+	}
+	return g.extract(g.VideoFrames, path)
+}
+
+// Document makes the thumbnail of an image or, if enabled, PDF document
+func (g *Generator) Document(path, mimetype string, data []byte) *Thumbnail {
+	switch {
+	case g == nil:
+		return nil
+	case strings.HasPrefix(mimetype, "image/"):
+		return g.Image(data)
+	case mimetype == "application/pdf":
+		return g.extract(g.PDFPages, path)
+	}
+	return nil
+}
+
+func (g *Generator) extract(extractor Extractor, path string) *Thumbnail {
+	if extractor == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), g.Timeout)
+	defer cancel()
+	img, err := extractor.Extract(ctx, path)
+	if err != nil {
+		log.Printf("Failed to extract thumbnail: %v", err)
+		return nil
+	}
+	thumb, err := FromImage(img)
+	if err != nil {
+		log.Printf("Failed to make thumbnail: %v", err)
+		return nil
+	}
+	return thumb
+}
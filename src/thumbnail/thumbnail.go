@@ -0,0 +1,133 @@
+package thumbnail
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+)
+
+// Size is the longest side of a thumbnail in pixels. WhatsApp shows the
+// thumbnail blurred and scaled up until the media is downloaded, so it only
+// needs to be tiny.
+const Size = 72
+
+// quality is the JPEG quality of thumbnails
+const quality = 75
+
+// maxPixels is the largest image decoded for a thumbnail, about 128MB once
+// decoded
+const maxPixels = 32 << 20
+
+// samples is the number of source pixels averaged per thumbnail pixel along
+// each axis
+const samples = 4
+
+// Thumbnail is a JPEG preview of an image, video or document
+type Thumbnail struct {
+	JPEG   []byte
+	Width  int // Of the thumbnail
+	Height int
+	// SourceWidth and SourceHeight are the size of the image, video frame
+	// or page the thumbnail was made from
+	SourceWidth  int
+	SourceHeight int
+}
+
+// FromImage scales img down to fit in Size×Size and encodes it as JPEG
+func FromImage(img image.Image) (*Thumbnail, error) {
+	bounds := img.Bounds()
+	if bounds.Empty() {
+		return nil, fmt.Errorf("image is empty")
+	}
+	width, height := fit(bounds.Dx(), bounds.Dy())
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, resize(img, width, height), &jpeg.Options{Quality: quality}); err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	return &Thumbnail{
+		JPEG:         buf.Bytes(),
+		Width:        width,
+		Height:       height,
+		SourceWidth:  bounds.Dx(),
+		SourceHeight: bounds.Dy(),
+	}, nil
+}
+
+// FromData decodes a JPEG, PNG or GIF image and makes its thumbnail. The
+// size is checked from the header first, since a small file can declare
+// dimensions that take gigabytes to decode.
+func FromData(data []byte) (*Thumbnail, error) {
+	img, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+	return FromImage(img)
+}
+
+// decode decodes an image unless its header declares more than maxPixels
+func decode(data []byte) (image.Image, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > maxPixels {
+		return nil, fmt.Errorf("image of %dx%d is too large for a thumbnail", cfg.Width, cfg.Height)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	return img, nil
+}
+
+// fit scales width and height down to fit in Size×Size, keeping the aspect
+// ratio; smaller images keep their size
+func fit(width, height int) (int, int) {
+	if width <= Size && height <= Size {
+		return width, height
+	}
+	if width >= height {
+		return Size, max(1, height*Size/width)
+	}
+	return max(1, width*Size/height), Size
+}
+
+// resize scales src to width×height by averaging a grid of samples under
+// each destination pixel. Sampling instead of visiting every source pixel
+// keeps large photos fast while still smoothing them.
+func resize(src image.Image, width, height int) *image.RGBA {
+	bounds := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			var r, g, b, a uint32
+			for sy := range samples {
+				srcY := bounds.Min.Y + (y*samples+sy)*bounds.Dy()/(height*samples)
+				for sx := range samples {
+					srcX := bounds.Min.X + (x*samples+sx)*bounds.Dx()/(width*samples)
+					pr, pg, pb, pa := src.At(srcX, srcY).RGBA()
+					r, g, b, a = r+pr, g+pg, b+pb, a+pa
+				}
+			}
+			n := uint32(samples * samples)
+			dst.SetRGBA(x, y, flatten(r/n, g/n, b/n, a/n))
+		}
+	}
+	return dst
+}
+
+// flatten puts a premultiplied 16-bit color on a white background, since
+// JPEG has no transparency
+func flatten(r, g, b, a uint32) color.RGBA {
+	white := 0xffff - a
+	return color.RGBA{
+		R: uint8((r + white) >> 8),
+		G: uint8((g + white) >> 8),
+		B: uint8((b + white) >> 8),
+		A: 0xff,
+	}
+}
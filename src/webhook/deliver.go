@@ -0,0 +1,186 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// idleWait is how long a worker sleeps when nothing is queued; new events
+// wake it earlier
+const idleWait = time.Hour
+
+// Start runs the writer of queued events and one delivery worker per URL
+// until Close is called
+func (o *Outbox) Start() {
+	o.wg.Add(1)
+	go o.store()
+	for url, wake := range o.wake {
+		o.wg.Add(1)
+		go o.run(url, wake)
+	}
+}
+
+// Close stops the workers, cancelling requests in flight; their deliveries
+// are retried after a restart. Queued events are written first.
+func (o *Outbox) Close() {
+	o.cancel()
+	o.wg.Wait()
+}
+
+// run delivers the due events of url in queue order, then sleeps until the
+// next retry is due or a new event arrives
+func (o *Outbox) run(url string, wake chan struct{}) {
+	defer o.wg.Done()
+	for {
+		wait, err := o.deliverDue(url)
+		if err != nil {
+			log.Printf("Webhook worker for %s failed: %v", url, err)
+			wait = o.cfg.MinBackoff
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-o.ctx.Done():
+			timer.Stop()
+			return
+		case <-wake:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// deliverDue sends every due event of url and returns the time until the
+// next one is due
+func (o *Outbox) deliverDue(url string) (time.Duration, error) {
+	for o.ctx.Err() == nil {
+		var id int64
+		var eventID, eventType string
+		var body []byte
+		var attempts int
+		err := o.db.QueryRow(`SELECT id, event_id, event_type, body, attempts FROM bot_webhook_outbox
+			WHERE dead = 0 AND url = ? AND next_attempt <= ? ORDER BY next_attempt, id LIMIT 1`,
+			url, time.Now().UnixMilli()).Scan(&id, &eventID, &eventType, &body, &attempts)
+		if errors.Is(err, sql.ErrNoRows) {
+			break
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read webhook outbox: %w", err)
+		}
+
+		attempts++
+		status, err := o.post(url, id, eventID, eventType, attempts, body)
+		if o.ctx.Err() != nil {
+			// Shutting down; the attempt doesn't count
+			return 0, nil
+		}
+		if err := o.finish(id, attempts, status, err); err != nil {
+			return 0, err
+		}
+	}
+
+	var next sql.NullInt64
+	err := o.db.QueryRow(`SELECT MIN(next_attempt) FROM bot_webhook_outbox WHERE dead = 0 AND url = ?`, url).Scan(&next)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read webhook outbox: %w", err)
+	}
+	if !next.Valid {
+		return idleWait, nil
+	}
+	return max(time.Until(time.UnixMilli(next.Int64)), 0), nil
+}
+
+// finish removes a delivered event, or schedules the next attempt of a
+// failed one and dead-letters it once it ran out of attempts
+func (o *Outbox) finish(id int64, attempts, status int, deliveryErr error) error {
+	if deliveryErr == nil {
+		if _, err := o.db.Exec(`DELETE FROM bot_webhook_outbox WHERE id = ?`, id); err != nil {
+			return fmt.Errorf("failed to remove delivered webhook: %w", err)
+		}
+		return nil
+	}
+
+	now := time.Now()
+	dead := attempts >= o.cfg.MaxAttempts
+	if dead {
+		log.Printf("Webhook delivery %d dead after %d attempts: %v", id, attempts, deliveryErr)
+	}
+	_, err := o.db.Exec(`UPDATE bot_webhook_outbox SET attempts = ?, next_attempt = ?, last_status = ?, last_error = ?, dead = ?, updated_at = ?
+		WHERE id = ?`, attempts, now.Add(o.backoff(attempts)).UnixMilli(), status, deliveryErr.Error(), dead, now.UnixMilli(), id)
+	if err != nil {
+		return fmt.Errorf("failed to reschedule webhook: %w", err)
+	}
+	return nil
+}
+
+// backoff doubles the delay after every failed attempt, up to MaxBackoff,
+// with up to 20% jitter so endpoints coming back aren't hit all at once
+func (o *Outbox) backoff(attempts int) time.Duration {
+	delay := o.cfg.MinBackoff
+	for i := 1; i < attempts && delay < o.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	delay = min(delay, o.cfg.MaxBackoff)
+	if delay <= 0 {
+		return 0
+	}
+	return delay + rand.N(delay/5+1)
+}
+
+// post sends one event. Any 2xx status counts as delivered.
+func (o *Outbox) post(url string, id int64, eventID, eventType string, attempt int, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(o.ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "whatsappBotGo-webhook")
+	req.Header.Set("X-Webhook-ID", eventID)
+	req.Header.Set("X-Webhook-Event", eventType)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatInt(id, 10))
+	req.Header.Set("X-Webhook-Attempt", strconv.Itoa(attempt))
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	if o.cfg.Secret != "" {
+		req.Header.Set("X-Webhook-Signature", "sha256="+sign(o.cfg.Secret, timestamp, body))
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg := fmt.Sprintf("unexpected status %s", resp.Status)
+		if text := strings.TrimSpace(string(snippet)); text != "" {
+			msg += ": " + text
+		}
+		return resp.StatusCode, errors.New(msg)
+	}
+	return resp.StatusCode, nil
+}
+
+// sign returns the hex HMAC-SHA256 of "<timestamp>.<body>". Including the
+// timestamp lets receivers reject replayed requests.
+func sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
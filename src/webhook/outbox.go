@@ -0,0 +1,393 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"whatsappBotGo/src/functions"
+)
+
+// maxQueued is how many events can wait to be written to the outbox before
+// Enqueue blocks
+const maxQueued = 1024
+
+var (
+	// ErrClosed is returned when queueing an event after Close
+	ErrClosed = errors.New("webhook outbox is closed")
+	// ErrNotFound is returned for unknown dead deliveries
+	ErrNotFound = errors.New("delivery not found")
+	// ErrURLRemoved is returned when replaying a delivery for a URL that is
+	// no longer configured
+	ErrURLRemoved = errors.New("webhook url is no longer configured")
+)
+
+// Config lists the webhook endpoints and how deliveries are retried
+type Config struct {
+	URLs        []string
+	Secret      string   // Signs every request; empty sends them unsigned
+	Types       []string // Event types to deliver; empty delivers all
+	MaxAttempts int      // Attempts before a delivery is dead-lettered
+	MinBackoff  time.Duration
+	MaxBackoff  time.Duration
+	Timeout     time.Duration // Per request
+}
+
+// ConfigFromEnv reads the WEBHOOK_* environment variables
+func ConfigFromEnv() Config {
+	return Config{
+		URLs:        splitList(functions.GetEnv("WEBHOOK_URLS", "")),
+		Secret:      functions.GetEnv("WEBHOOK_SECRET", ""),
+		Types:       splitList(functions.GetEnv("WEBHOOK_EVENTS", "")),
+		MaxAttempts: max(functions.GetEnvInt("WEBHOOK_MAX_ATTEMPTS", 10), 1),
+		MinBackoff:  time.Duration(functions.GetEnvInt("WEBHOOK_RETRY_MIN_SECONDS", 5)) * time.Second,
+		MaxBackoff:  time.Duration(functions.GetEnvInt("WEBHOOK_RETRY_MAX_SECONDS", 3600)) * time.Second,
+		Timeout:     time.Duration(functions.GetEnvInt("WEBHOOK_TIMEOUT_SECONDS", 10)) * time.Second,
+	}
+}
+
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Delivery is one event queued for one endpoint
+type Delivery struct {
+	ID         int64           `json:"id"`
+	URL        string          `json:"url"`
+	EventID    string          `json:"event_id"`
+	EventType  string          `json:"event_type"`
+	Attempts   int             `json:"attempts"`
+	LastStatus int             `json:"last_status,omitempty"` // HTTP status of the last attempt
+	LastError  string          `json:"last_error,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+	Event      json.RawMessage `json:"event"`
+}
+
+// DeadPage is one page of dead deliveries, newest first
+type DeadPage struct {
+	Deliveries []Delivery `json:"deliveries"`
+	NextBefore int64      `json:"next_before,omitempty"` // Empty on the last page
+}
+
+// Stats counts queued and dead deliveries
+type Stats struct {
+	Pending int `json:"pending"`
+	Dead    int `json:"dead"`
+}
+
+// Outbox stores events for webhook endpoints in SQLite until they are
+// delivered, so a restart doesn't lose them. Deliveries that keep failing
+// are moved to the dead-letter list, from where they can be replayed.
+type Outbox struct {
+	db     *sql.DB
+	cfg    Config
+	types  map[string]bool
+	client *http.Client
+
+	queue  chan queued              // Events not written to the outbox yet
+	wake   map[string]chan struct{} // One worker per URL
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewOutbox creates the outbox table if needed
+func NewOutbox(db *sql.DB, cfg Config) (*Outbox, error) {
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS bot_webhook_outbox (
+			id           INTEGER PRIMARY KEY AUTOINCREMENT,
+			url          TEXT NOT NULL,
+			event_id     TEXT NOT NULL,
+			event_type   TEXT NOT NULL,
+			body         BLOB NOT NULL,
+			attempts     INTEGER NOT NULL DEFAULT 0,
+			next_attempt INTEGER NOT NULL,
+			last_status  INTEGER NOT NULL DEFAULT 0,
+			last_error   TEXT NOT NULL DEFAULT '',
+			dead         INTEGER NOT NULL DEFAULT 0,
+			created_at   INTEGER NOT NULL,
+			updated_at   INTEGER NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS bot_webhook_outbox_due ON bot_webhook_outbox (dead, url, next_attempt)`,
+	}
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			return nil, fmt.Errorf("failed to create webhook outbox: %w", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	o := &Outbox{
+		db:     db,
+		cfg:    cfg,
+		types:  make(map[string]bool),
+		client: &http.Client{Timeout: cfg.Timeout},
+		queue:  make(chan queued, maxQueued),
+		wake:   make(map[string]chan struct{}),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	for _, t := range cfg.Types {
+		o.types[t] = true
+	}
+	for _, url := range cfg.URLs {
+		o.wake[url] = make(chan struct{}, 1)
+	}
+	if err := o.orphan(); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// orphan dead-letters deliveries for URLs that are no longer configured,
+// since no worker would ever pick them up
+func (o *Outbox) orphan() error {
+	query := `UPDATE bot_webhook_outbox SET dead = 1, last_error = 'webhook url no longer configured', updated_at = ? WHERE dead = 0`
+	args := []any{time.Now().UnixMilli()}
+	if len(o.cfg.URLs) > 0 {
+		query += ` AND url NOT IN (?` + strings.Repeat(`, ?`, len(o.cfg.URLs)-1) + `)`
+		for _, url := range o.cfg.URLs {
+			args = append(args, url)
+		}
+	}
+	if _, err := o.db.Exec(query, args...); err != nil {
+		return fmt.Errorf("failed to update webhook outbox: %w", err)
+	}
+	return nil
+}
+
+// queued is an event waiting to be written to the outbox
+type queued struct {
+	eventID   string
+	eventType string
+	body      []byte
+}
+
+// Enqueue queues an encoded event for every endpoint. Events of types that
+// are not configured are skipped. The event is written to the outbox in the
+// background, so a slow disk doesn't hold up the caller unless maxQueued
+// events are already waiting.
+func (o *Outbox) Enqueue(eventID, eventType string, body []byte) error {
+	if len(o.types) > 0 && !o.types[eventType] {
+		return nil
+	}
+	select {
+	case o.queue <- queued{eventID: eventID, eventType: eventType, body: body}:
+		return nil
+	case <-o.ctx.Done():
+		return ErrClosed
+	}
+}
+
+// store writes queued events to the outbox until Close is called, then
+// writes the ones left
+func (o *Outbox) store() {
+	defer o.wg.Done()
+	for {
+		select {
+		case q := <-o.queue:
+			o.insert(append([]queued{q}, o.drain()...))
+		case <-o.ctx.Done():
+			o.insert(o.drain())
+			return
+		}
+	}
+}
+
+// drain takes the events waiting in the queue without blocking
+func (o *Outbox) drain() []queued {
+	var batch []queued
+	for len(batch) < maxQueued {
+		select {
+		case q := <-o.queue:
+			batch = append(batch, q)
+		default:
+			return batch
+		}
+	}
+	return batch
+}
+
+// insert writes a batch of events for every endpoint in one transaction
+// and wakes the workers
+func (o *Outbox) insert(batch []queued) {
+	if len(batch) == 0 {
+		return
+	}
+	if err := o.insertTx(batch); err != nil {
+		log.Printf("Failed to queue %d webhook events: %v", len(batch), err)
+		return
+	}
+	for _, url := range o.cfg.URLs {
+		o.notify(url)
+	}
+}
+
+func (o *Outbox) insertTx(batch []queued) error {
+	tx, err := o.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UnixMilli()
+	for _, q := range batch {
+		for _, url := range o.cfg.URLs {
+			_, err := tx.Exec(`INSERT INTO bot_webhook_outbox (url, event_id, event_type, body, next_attempt, created_at, updated_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?)`, url, q.eventID, q.eventType, q.body, now, now, now)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// notify wakes the worker of url without blocking
+func (o *Outbox) notify(url string) {
+	select {
+	case o.wake[url] <- struct{}{}:
+	default:
+	}
+}
+
+// Dead lists dead deliveries with an id below before, newest first; before
+// 0 starts at the newest
+func (o *Outbox) Dead(before int64, limit int) (*DeadPage, error) {
+	query := `SELECT id, url, event_id, event_type, body, attempts, last_status, last_error, created_at, updated_at
+		FROM bot_webhook_outbox WHERE dead = 1`
+	var args []any
+	if before > 0 {
+		query += ` AND id < ?`
+		args = append(args, before)
+	}
+	query += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, limit+1)
+
+	rows, err := o.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dead webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	page := &DeadPage{Deliveries: []Delivery{}}
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		page.Deliveries = append(page.Deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(page.Deliveries) > limit {
+		page.Deliveries = page.Deliveries[:limit]
+		page.NextBefore = page.Deliveries[limit-1].ID
+	}
+	return page, nil
+}
+
+// DeadDelivery returns one dead delivery
+func (o *Outbox) DeadDelivery(id int64) (*Delivery, error) {
+	row := o.db.QueryRow(`SELECT id, url, event_id, event_type, body, attempts, last_status, last_error, created_at, updated_at
+		FROM bot_webhook_outbox WHERE id = ? AND dead = 1`, id)
+	d, err := scanDelivery(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func scanDelivery(row interface{ Scan(...any) error }) (Delivery, error) {
+	var d Delivery
+	var body []byte
+	var created, updated int64
+	err := row.Scan(&d.ID, &d.URL, &d.EventID, &d.EventType, &body, &d.Attempts, &d.LastStatus, &d.LastError, &created, &updated)
+	if errors.Is(err, sql.ErrNoRows) {
+		return d, err
+	}
+	if err != nil {
+		return d, fmt.Errorf("failed to read webhook delivery: %w", err)
+	}
+	d.Event = body
+	d.CreatedAt = time.UnixMilli(created)
+	d.UpdatedAt = time.UnixMilli(updated)
+	return d, nil
+}
+
+// Replay queues a dead delivery again with a fresh set of attempts
+func (o *Outbox) Replay(id int64) error {
+	d, err := o.DeadDelivery(id)
+	if err != nil {
+		return err
+	}
+	if _, ok := o.wake[d.URL]; !ok {
+		return ErrURLRemoved
+	}
+	now := time.Now().UnixMilli()
+	_, err = o.db.Exec(`UPDATE bot_webhook_outbox SET dead = 0, attempts = 0, next_attempt = ?, updated_at = ?
+		WHERE id = ? AND dead = 1`, now, now, id)
+	if err != nil {
+		return fmt.Errorf("failed to replay webhook: %w", err)
+	}
+	o.notify(d.URL)
+	return nil
+}
+
+// ReplayAll queues every dead delivery of a configured URL again and
+// returns how many were queued
+func (o *Outbox) ReplayAll() (int, error) {
+	total := 0
+	now := time.Now().UnixMilli()
+	for _, url := range o.cfg.URLs {
+		res, err := o.db.Exec(`UPDATE bot_webhook_outbox SET dead = 0, attempts = 0, next_attempt = ?, updated_at = ?
+			WHERE dead = 1 AND url = ?`, now, now, url)
+		if err != nil {
+			return total, fmt.Errorf("failed to replay webhooks: %w", err)
+		}
+		n, _ := res.RowsAffected()
+		total += int(n)
+		o.notify(url)
+	}
+	return total, nil
+}
+
+// Discard removes a dead delivery
+func (o *Outbox) Discard(id int64) error {
+	res, err := o.db.Exec(`DELETE FROM bot_webhook_outbox WHERE id = ? AND dead = 1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to discard webhook: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Stats counts pending and dead deliveries
+func (o *Outbox) Stats() (Stats, error) {
+	var s Stats
+	err := o.db.QueryRow(`SELECT COALESCE(SUM(dead = 0), 0), COALESCE(SUM(dead = 1), 0) FROM bot_webhook_outbox`).Scan(&s.Pending, &s.Dead)
+	if err != nil {
+		return s, fmt.Errorf("failed to count webhooks: %w", err)
+	}
+	return s, nil
+}
@@ -2,50 +2,123 @@ package whats
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"runtime/debug"
+	"sync"
 
 	"go.mau.fi/whatsmeow/types/events"
 )
 
-// EventHandler is called when WhatsApp events arrive
+// EventHandler is called when WhatsApp events arrive. It is kept for handlers
+// written before typed subscriptions; new code should use On.
 type EventHandler interface {
 	HandleMessage(*events.Message)
 	HandleReceipt(*events.Receipt)
 	HandlePresence(*events.Presence)
 }
 
-// Events package skeleton for future event routing and processing
+// subscription is a registered handler; fn ignores events of other types
+type subscription struct {
+	id   int
+	name string
+	fn   func(evt any) error
+}
+
+// Events is the event bus between whatsmeow and the rest of the bot.
+// Handlers subscribe to the event types they care about and run in the
+// order they were registered. An error or panic in one handler is logged
+// and does not keep the others from running.
 type Events struct {
-	handlers []EventHandler
+	mu     sync.RWMutex
+	subs   []subscription
+	nextID int
 }
 
 // NewEvents creates a new Events dispatcher
 func NewEvents() *Events {
-	return &Events{handlers: make([]EventHandler, 0)}
+	return &Events{}
+}
+
+// On subscribes fn to events of type T, which is usually a pointer to a
+// whatsmeow event such as *events.Connected or *events.CallOffer. name
+// identifies the handler in logs. It returns a function that unsubscribes.
+func On[T any](e *Events, name string, fn func(evt T) error) (unsubscribe func()) {
+	return e.subscribe(name, func(evt any) error {
+		if v, ok := evt.(T); ok {
+			return fn(v)
+		}
+		return nil
+	})
+}
+
+// OnAny subscribes fn to every event
+func (e *Events) OnAny(name string, fn func(evt any) error) (unsubscribe func()) {
+	return e.subscribe(name, fn)
+}
+
+func (e *Events) subscribe(name string, fn func(evt any) error) func() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.nextID++
+	id := e.nextID
+	e.subs = append(e.subs, subscription{id: id, name: name, fn: fn})
+
+	return func() {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		for i, s := range e.subs {
+			if s.id == id {
+				e.subs = append(e.subs[:i:i], e.subs[i+1:]...)
+				return
+			}
+		}
+	}
 }
 
 // RegisterHandler registers an event handler
 func (e *Events) RegisterHandler(h EventHandler) {
-	e.handlers = append(e.handlers, h)
+	On(e, fmt.Sprintf("%T", h), func(evt *events.Message) error { h.HandleMessage(evt); return nil })
+	On(e, fmt.Sprintf("%T", h), func(evt *events.Receipt) error { h.HandleReceipt(evt); return nil })
+	On(e, fmt.Sprintf("%T", h), func(evt *events.Presence) error { h.HandlePresence(evt); return nil })
 }
 
-// Dispatch sends an event to all registered handlers
+// Dispatch sends an event to all subscribed handlers and returns their
+// errors joined together
 func (e *Events) Dispatch(ctx context.Context, evt interface{}) error {
-	switch v := evt.(type) {
-	case *events.Message:
-		for _, h := range e.handlers {
-			h.HandleMessage(v)
+	e.mu.RLock()
+	subs := e.subs
+	e.mu.RUnlock()
+
+	var errs []error
+	for _, s := range subs {
+		if ctx.Err() != nil {
+			errs = append(errs, ctx.Err())
+			break
 		}
-	case *events.Receipt:
-		for _, h := range e.handlers {
-			h.HandleReceipt(v)
+		if err := s.call(evt); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.name, err))
 		}
-	case *events.Presence:
-		for _, h := range e.handlers {
-			h.HandlePresence(v)
+	}
+	return errors.Join(errs...)
+}
+
+// call runs the handler, turning a panic into an error
+func (s subscription) call(evt any) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Panic in event handler %s for %T: %v\n%s", s.name, evt, r, debug.Stack())
+			err = fmt.Errorf("panic: %v", r)
 		}
-	default:
-		return fmt.Errorf("unknown event type: %T", evt)
+	}()
+	return s.fn(evt)
+}
+
+// Handle dispatches evt and logs handler errors. Pass it to
+// whatsmeow.Client.AddEventHandler to feed the bus.
+func (e *Events) Handle(evt interface{}) {
+	if err := e.Dispatch(context.Background(), evt); err != nil {
+		log.Printf("Failed to handle %T: %v", evt, err)
 	}
-	return nil
 }
@@ -0,0 +1,266 @@
+package whats
+
+import (
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	waE2E "go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// MessageType classifies an incoming message
+type MessageType string
+
+const (
+	TypeText         MessageType = "text"
+	TypeImage        MessageType = "image"
+	TypeVideo        MessageType = "video"
+	TypeGIF          MessageType = "gif"
+	TypeAudio        MessageType = "audio"
+	TypeVoice        MessageType = "voice"
+	TypeDocument     MessageType = "document"
+	TypeSticker      MessageType = "sticker"
+	TypeLocation     MessageType = "location"
+	TypeLiveLocation MessageType = "live_location"
+	TypeContact      MessageType = "contact"
+	TypeContacts     MessageType = "contacts"
+	TypePoll         MessageType = "poll"
+	TypeReaction     MessageType = "reaction"
+	TypeEdit         MessageType = "edit"
+	TypeDelete       MessageType = "delete"
+	TypeProtocol     MessageType = "protocol" // Other control messages
+	TypeUnknown      MessageType = "unknown"
+)
+
+// Media describes a media attachment without its content
+type Media struct {
+	ID            string `json:"id,omitempty"` // Set once downloaded into the media store
+	Mimetype      string `json:"mimetype,omitempty"`
+	FileName      string `json:"file_name,omitempty"`
+	FileLength    uint64 `json:"file_length,omitempty"`
+	FileSHA256    []byte `json:"file_sha256,omitempty"`
+	FileEncSHA256 []byte `json:"file_enc_sha256,omitempty"`
+	MediaKey      []byte `json:"-"`
+	DirectPath    string `json:"-"`
+	Width         uint32 `json:"width,omitempty"`
+	Height        uint32 `json:"height,omitempty"`
+	Seconds       uint32 `json:"seconds,omitempty"`
+	PageCount     uint32 `json:"page_count,omitempty"`
+	Animated      bool   `json:"animated,omitempty"`
+}
+
+// Location is a shared static or live location
+type Location struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Name      string  `json:"name,omitempty"`
+	Address   string  `json:"address,omitempty"`
+	URL       string  `json:"url,omitempty"`
+	Live      bool    `json:"live,omitempty"`
+}
+
+// Contact is a shared contact card
+type Contact struct {
+	DisplayName string `json:"display_name"`
+	VCard       string `json:"vcard"`
+}
+
+// Reaction is an emoji reaction to another message
+type Reaction struct {
+	TargetID string `json:"target_id"`
+	Emoji    string `json:"emoji"` // Empty when a reaction is removed
+}
+
+// IncomingMessage is a normalized view of a WhatsApp message, independent of
+// which waE2E.Message variant carried it
+type IncomingMessage struct {
+	ID        string      `json:"id"`
+	Chat      types.JID   `json:"chat"`
+	Sender    types.JID   `json:"sender"`
+	SenderAlt types.JID   `json:"sender_alt,omitempty"`
+	PushName  string      `json:"push_name,omitempty"`
+	Timestamp time.Time   `json:"timestamp"`
+	IsGroup   bool        `json:"is_group"`
+	IsFromMe  bool        `json:"is_from_me"`
+	Type      MessageType `json:"type"`
+
+	// Text is the message body, or the caption for media messages
+	Text     string   `json:"text,omitempty"`
+	QuotedID string   `json:"quoted_id,omitempty"`
+	Mentions []string `json:"mentions,omitempty"`
+
+	Media       *Media    `json:"media,omitempty"`
+	Location    *Location `json:"location,omitempty"`
+	Contacts    []Contact `json:"contacts,omitempty"`
+	PollName    string    `json:"poll_name,omitempty"`
+	PollOptions []string  `json:"poll_options,omitempty"`
+	Reaction    *Reaction `json:"reaction,omitempty"`
+
+	// TargetID is the message an edit or deletion applies to
+	TargetID string `json:"target_id,omitempty"`
+
+	downloadable whatsmeow.DownloadableMessage
+}
+
+// Downloadable returns the media message that can be passed to
+// whatsmeow.Client.Download, or nil if the message has no media
+func (m *IncomingMessage) Downloadable() whatsmeow.DownloadableMessage {
+	return m.downloadable
+}
+
+// HasMedia reports whether the message carries a downloadable attachment
+func (m *IncomingMessage) HasMedia() bool {
+	return m.downloadable != nil
+}
+
+// ParseMessage normalizes a whatsmeow message event
+func ParseMessage(evt *events.Message) *IncomingMessage {
+	m := &IncomingMessage{
+		ID:        evt.Info.ID,
+		Chat:      evt.Info.Chat,
+		Sender:    evt.Info.Sender,
+		SenderAlt: evt.Info.SenderAlt,
+		PushName:  evt.Info.PushName,
+		Timestamp: evt.Info.Timestamp,
+		IsGroup:   evt.Info.IsGroup,
+		IsFromMe:  evt.Info.IsFromMe,
+		Type:      TypeUnknown,
+	}
+
+	msg := evt.Message
+	if msg == nil {
+		return m
+	}
+
+	var ctx *waE2E.ContextInfo
+	switch {
+	case msg.GetConversation() != "":
+		m.Type, m.Text = TypeText, msg.GetConversation()
+	case msg.GetExtendedTextMessage() != nil:
+		ext := msg.GetExtendedTextMessage()
+		m.Type, m.Text, ctx = TypeText, ext.GetText(), ext.GetContextInfo()
+	case msg.GetImageMessage() != nil:
+		img := msg.GetImageMessage()
+		m.Type, m.Text, ctx = TypeImage, img.GetCaption(), img.GetContextInfo()
+		m.Media = &Media{
+			Mimetype: img.GetMimetype(), FileLength: img.GetFileLength(),
+			FileSHA256: img.GetFileSHA256(), FileEncSHA256: img.GetFileEncSHA256(),
+			MediaKey: img.GetMediaKey(), DirectPath: img.GetDirectPath(),
+			Width: img.GetWidth(), Height: img.GetHeight(),
+		}
+		m.downloadable = img
+	case msg.GetVideoMessage() != nil:
+		vid := msg.GetVideoMessage()
+		m.Type, m.Text, ctx = TypeVideo, vid.GetCaption(), vid.GetContextInfo()
+		if vid.GetGifPlayback() {
+			m.Type = TypeGIF
+		}
+		m.Media = &Media{
+			Mimetype: vid.GetMimetype(), FileLength: vid.GetFileLength(),
+			FileSHA256: vid.GetFileSHA256(), FileEncSHA256: vid.GetFileEncSHA256(),
+			MediaKey: vid.GetMediaKey(), DirectPath: vid.GetDirectPath(),
+			Width: vid.GetWidth(), Height: vid.GetHeight(), Seconds: vid.GetSeconds(),
+			Animated: vid.GetGifPlayback(),
+		}
+		m.downloadable = vid
+	case msg.GetAudioMessage() != nil:
+		aud := msg.GetAudioMessage()
+		m.Type, ctx = TypeAudio, aud.GetContextInfo()
+		if aud.GetPTT() {
+			m.Type = TypeVoice
+		}
+		m.Media = &Media{
+			Mimetype: aud.GetMimetype(), FileLength: aud.GetFileLength(),
+			FileSHA256: aud.GetFileSHA256(), FileEncSHA256: aud.GetFileEncSHA256(),
+			MediaKey: aud.GetMediaKey(), DirectPath: aud.GetDirectPath(),
+			Seconds: aud.GetSeconds(),
+		}
+		m.downloadable = aud
+	case msg.GetDocumentMessage() != nil || msg.GetDocumentWithCaptionMessage() != nil:
+		doc := msg.GetDocumentMessage()
+		if doc == nil {
+			doc = msg.GetDocumentWithCaptionMessage().GetMessage().GetDocumentMessage()
+		}
+		m.Type, m.Text, ctx = TypeDocument, doc.GetCaption(), doc.GetContextInfo()
+		m.Media = &Media{
+			Mimetype: doc.GetMimetype(), FileName: doc.GetFileName(), FileLength: doc.GetFileLength(),
+			FileSHA256: doc.GetFileSHA256(), FileEncSHA256: doc.GetFileEncSHA256(),
+			MediaKey: doc.GetMediaKey(), DirectPath: doc.GetDirectPath(),
+			PageCount: doc.GetPageCount(),
+		}
+		m.downloadable = doc
+	case msg.GetStickerMessage() != nil:
+		st := msg.GetStickerMessage()
+		m.Type, ctx = TypeSticker, st.GetContextInfo()
+		m.Media = &Media{
+			Mimetype: st.GetMimetype(), FileLength: st.GetFileLength(),
+			FileSHA256: st.GetFileSHA256(), FileEncSHA256: st.GetFileEncSHA256(),
+			MediaKey: st.GetMediaKey(), DirectPath: st.GetDirectPath(),
+			Width: st.GetWidth(), Height: st.GetHeight(), Animated: st.GetIsAnimated(),
+		}
+		m.downloadable = st
+	case msg.GetLocationMessage() != nil:
+		loc := msg.GetLocationMessage()
+		m.Type, m.Text, ctx = TypeLocation, loc.GetComment(), loc.GetContextInfo()
+		m.Location = &Location{
+			Latitude: loc.GetDegreesLatitude(), Longitude: loc.GetDegreesLongitude(),
+			Name: loc.GetName(), Address: loc.GetAddress(), URL: loc.GetURL(), Live: loc.GetIsLive(),
+		}
+	case msg.GetLiveLocationMessage() != nil:
+		loc := msg.GetLiveLocationMessage()
+		m.Type, m.Text, ctx = TypeLiveLocation, loc.GetCaption(), loc.GetContextInfo()
+		m.Location = &Location{Latitude: loc.GetDegreesLatitude(), Longitude: loc.GetDegreesLongitude(), Live: true}
+	case msg.GetContactMessage() != nil:
+		c := msg.GetContactMessage()
+		m.Type, ctx = TypeContact, c.GetContextInfo()
+		m.Contacts = []Contact{{DisplayName: c.GetDisplayName(), VCard: c.GetVcard()}}
+	case msg.GetContactsArrayMessage() != nil:
+		arr := msg.GetContactsArrayMessage()
+		m.Type, ctx = TypeContacts, arr.GetContextInfo()
+		for _, c := range arr.GetContacts() {
+			m.Contacts = append(m.Contacts, Contact{DisplayName: c.GetDisplayName(), VCard: c.GetVcard()})
+		}
+	case pollCreation(msg) != nil:
+		poll := pollCreation(msg)
+		m.Type, m.PollName, ctx = TypePoll, poll.GetName(), poll.GetContextInfo()
+		for _, opt := range poll.GetOptions() {
+			m.PollOptions = append(m.PollOptions, opt.GetOptionName())
+		}
+	case msg.GetReactionMessage() != nil:
+		r := msg.GetReactionMessage()
+		m.Type = TypeReaction
+		m.Reaction = &Reaction{TargetID: r.GetKey().GetID(), Emoji: r.GetText()}
+	case msg.GetProtocolMessage() != nil:
+		proto := msg.GetProtocolMessage()
+		m.Type = TypeProtocol
+		switch proto.GetType() {
+		case waE2E.ProtocolMessage_MESSAGE_EDIT:
+			// Text is the new text or caption of the edited message
+			edited := ParseMessage(&events.Message{Info: evt.Info, Message: proto.GetEditedMessage()})
+			m.Type, m.TargetID, m.Text = TypeEdit, proto.GetKey().GetID(), edited.Text
+		case waE2E.ProtocolMessage_REVOKE:
+			m.Type, m.TargetID = TypeDelete, proto.GetKey().GetID()
+		}
+	}
+
+	if ctx != nil {
+		m.QuotedID = ctx.GetStanzaID()
+		m.Mentions = ctx.GetMentionedJID()
+	}
+	return m
+}
+
+// pollCreation returns the poll of any poll message version
+func pollCreation(msg *waE2E.Message) *waE2E.PollCreationMessage {
+	for _, p := range []*waE2E.PollCreationMessage{
+		msg.GetPollCreationMessage(),
+		msg.GetPollCreationMessageV2(),
+		msg.GetPollCreationMessageV3(),
+	} {
+		if p != nil {
+			return p
+		}
+	}
+	return nil
+}